@@ -2,35 +2,136 @@
 //
 // Usage:
 //
-//	mysql_mcp_server
+//	mysql_mcp_server [-transport stdio|http+sse] [-addr :8080]
 //
 // Supported tools:
 //   - mysql_query: Execute MySQL queries (read-only, SELECT statements)
 //   - mysql_execute: Execute MySQL update operations (non-query statements)
+//   - mysql_list_connections: List the named database connections available to this server
+//   - mysql_explain_route: Show the shard routing plan for a SQL statement
+//   - mysql_list_databases, mysql_list_tables, mysql_describe_table,
+//     mysql_show_indexes, mysql_show_foreign_keys, mysql_show_create: schema
+//     introspection, cached with a TTL and backed by the
+//     schema://<conn>/<db>/<table> resource. The "connection" argument's
+//     schema enumerates the connections declared in the config file.
+//   - mysql_refresh_schema: invalidate cached schema introspection results
+//
+// mysql_query statements are parsed with xwb1989/sqlparser and rejected unless
+// they are SELECT/SHOW/DESCRIBE/EXPLAIN; LIMIT-less SELECTs get a LIMIT
+// appended automatically, results are truncated to max_rows/a rough max_bytes,
+// and execution on a connection pool is bounded by a query timeout; queries
+// run inside a tx_id transaction or routed across shards are not bounded by
+// it. mysql_execute uses the same
+// AST-based check to reject DROP/TRUNCATE/ALTER instead of string prefix
+// matching. mysql_query_page runs a SELECT one page at a time via an
+// AST-rewritten LIMIT/OFFSET, returning a next_cursor token to resume from.
+// Both tools marshal DATETIME/DECIMAL/JSON/SET/BIT/BLOB columns by their
+// actual MySQL type instead of treating every column as text, and accept an
+// "encoding" argument to get a columnar ({columns, types, rows}) layout.
+//
+//   - mysql_begin, mysql_commit, mysql_rollback: open an explicit transaction
+//     on a connection and later commit or roll it back by tx_id; mysql_query
+//     and mysql_execute accept an optional tx_id to run inside it. Idle
+//     transactions are rolled back automatically after db.TxIdleTimeout.
+//   - mysql_savepoint: create a named savepoint inside an open transaction;
+//     mysql_rollback accepts an optional savepoint to roll back to it without
+//     ending the transaction.
+//   - mysql_tx_exec: run a batch of statements inside an open transaction,
+//     rolling the whole transaction back on the first failing statement.
+//   - mysql_health: report per-connection pool statistics, reachability, and
+//     a rolling log of recent slow queries (threshold configurable via
+//     MCP_SLOW_QUERY_THRESHOLD_MS). The same data is exposed over HTTP as
+//     /healthz and /metrics (Prometheus text format) when the config file
+//     sets metrics_addr; that listener is independent of the http+sse
+//     transport's own server and also runs alongside stdio.
+//
+// Every tool call is recorded as a JSON line to the file at MCP_AUDIT_LOG_PATH
+// (audit logging is off unless set), via pkg/audit, with the target
+// connection, redacted SQL, an argument hash, row count, duration, and any
+// error. pkg/redact masks values matching the config file's "redaction"
+// column-name/regex rules out of mysql_query/mysql_query_page results and out
+// of the SQL text reaching the audit log; a bare "SELECT *" against a table
+// with a redacted column is rejected unless the connection's permission sets
+// allow_raw_pii.
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
 	"github.com/ThinkInAIXYZ/go-mcp/server"
 	"github.com/ThinkInAIXYZ/go-mcp/transport"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/xwb1989/sqlparser"
+
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/audit"
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/config"
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/db"
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/redact"
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/router"
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/schema"
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/sqlsafety"
 )
 
-// Database connection
-var db *sql.DB
+// defaultConnection is used when a tool call does not specify a "connection" argument
+const defaultConnection = "default"
+
+// dbManager routes tool calls to the named connection pool they target
+var dbManager *db.DBManager
+
+// sqlRouter dispatches queries/executes against sharded tables declared in
+// the config file's shard_rules to the connections that hold each shard
+var sqlRouter *router.Router
+
+// schemaCache serves the schema introspection tools and the schema:// resource
+// template, caching information_schema lookups for a configurable TTL
+var schemaCache *schema.Cache
+
+// defaultQueryTimeout bounds how long mysql_query may run when a call does not
+// override it with "timeout_seconds"; overridable server-wide via
+// MCP_QUERY_TIMEOUT_SECONDS
+const defaultQueryTimeout = 30 * time.Second
 
-// Permission control flags
-var allowInsert, allowUpdate, allowDelete bool
+// maxRows caps how many rows mysql_query returns when a call does not
+// override it with "max_rows"; LIMIT-less SELECTs get "LIMIT maxRows+1"
+// injected so a truncated result can be detected without a second round trip.
+// Overridable server-wide via MCP_MAX_ROWS.
+var maxRows = sqlsafety.DefaultMaxRows
+
+// maxBytes caps the rough size of the row data mysql_query/mysql_query_page
+// return before truncating, regardless of max_rows, so a table with very wide
+// rows can't still blow past a reasonable response size. Overridable
+// server-wide via MCP_MAX_BYTES.
+var maxBytes = db.DefaultMaxBytes
+
+// queryTimeout is the server-wide default applied when a mysql_query call
+// does not set "timeout_seconds"
+var queryTimeout = defaultQueryTimeout
+
+// redactor masks values matching the config file's "redaction" rules out of
+// query results and SQL text before they leave the process or reach the
+// audit log. It is nil only if config.Redaction fails to compile, which is
+// fatal at startup; an empty Redaction config still yields a non-nil,
+// all-no-op Redactor.
+var redactor *redact.Redactor
+
+// auditLogger writes a structured JSON record of every tool call. It is nil
+// (a no-op) unless MCP_AUDIT_LOG_PATH is set.
+var auditLogger *audit.Logger
 
 // Development mode flag, controls whether to print detailed logs
 var isDev bool
@@ -39,6 +140,12 @@ var isDev bool
 var logEnabled bool
 
 func main() {
+	configPath := flag.String("config", "config.json", "path to the JSON file declaring database connections")
+	// websocket was part of the original transport suite but isn't offered
+	// here: see newTransport's "websocket" case for why go-mcp v0.1.14 rules
+	// it out.
+	transportFlag := flag.String("transport", "stdio", "transport to serve on: stdio or http+sse")
+	addr := flag.String("addr", ":8080", "listen address for the http+sse transport")
 	flag.Parse()
 
 	// Set development mode flag
@@ -70,54 +177,112 @@ func main() {
 		log.SetOutput(&nullWriter{})
 	}
 
-	// Read environment variables
-	mysqlHost := getEnvWithDefault("MYSQL_HOST", "127.0.0.1")
-	mysqlPort := getEnvWithDefault("MYSQL_PORT", "3306")
-	mysqlUser := getEnvWithDefault("MYSQL_USER", "root")
-	mysqlPass := getEnvWithDefault("MYSQL_PASS", "password")
-	defaultDB := getEnvWithDefault("DEFAULT_DATABASE", "test")
-
-	// Set permission control flags
-	allowInsert = getEnvWithDefault("ALLOW_INSERT", "false") == "true"
-	allowUpdate = getEnvWithDefault("ALLOW_UPDATE", "false") == "true"
-	allowDelete = getEnvWithDefault("ALLOW_DELETE", "false") == "true"
+	// Load the connection/permission configuration. config.LoadConfig takes it
+	// from environment variables when they are set, otherwise from the config
+	// file at configPath, and finally falls back to a single "default"
+	// connection when that file does not exist.
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
 
-	// Log environment variable information, only in development mode
 	if logEnabled {
-		log.Printf("Environment variables configuration:")
-		log.Printf("MYSQL_HOST: %s", mysqlHost)
-		log.Printf("MYSQL_PORT: %s", mysqlPort)
-		log.Printf("MYSQL_USER: %s", mysqlUser)
-		log.Printf("MYSQL_PASS: %s", mysqlPass)
-		log.Printf("DEFAULT_DATABASE: %s", defaultDB)
-		log.Printf("ALLOW_INSERT: %v", allowInsert)
-		log.Printf("ALLOW_UPDATE: %v", allowUpdate)
-		log.Printf("ALLOW_DELETE: %v", allowDelete)
+		connectionNames := make([]string, 0, len(cfg.Databases))
+		for name := range cfg.Databases {
+			connectionNames = append(connectionNames, name)
+		}
+		log.Printf("Configured connections: %v", connectionNames)
 		log.Printf("IS_DEV: %v", isDev)
 	}
 
-	// Build DSN
-	connectionDSN := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
-		mysqlUser, mysqlPass, mysqlHost, mysqlPort, defaultDB)
+	// Initialize the database connection pools
+	dbManager, err = db.NewDBManager(cfg)
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		log.Fatalf("Database connection failed: %v", err)
+	}
+	defer dbManager.Close()
+
+	// Build the shard router. It is harmless to construct even when no shard
+	// rules are configured: handleQuery/handleExecute only consult it for
+	// tables that have a matching rule.
+	sqlRouter = router.NewRouter(cfg.ShardRules, dbManager)
 
-	// Log DSN information, only in development mode
-	if logEnabled {
-		log.Printf("Using DSN: %s", connectionDSN)
-		// Log full startup command
-		log.Printf("Startup command: %v", os.Args)
+	// Build the schema introspection cache. MCP_SCHEMA_CACHE_TTL_SECONDS
+	// overrides the 5 minute default; an unparsable value falls back to it.
+	schemaTTL := schema.DefaultTTL
+	if ttlStr := os.Getenv("MCP_SCHEMA_CACHE_TTL_SECONDS"); ttlStr != "" {
+		if seconds, convErr := time.ParseDuration(ttlStr + "s"); convErr == nil {
+			schemaTTL = seconds
+		}
 	}
+	schemaCache = schema.NewCache(dbManager, schemaTTL)
 
-	// Initialize database
-	if err := initDB(connectionDSN); err != nil {
-		// Database connection failure is a critical error, should be logged to stderr even if logging is disabled
+	// Build the redactor from the config file's "redaction" rules; an
+	// unconfigured Redaction yields a no-op Redactor rather than nil, so
+	// callers never have to special-case "no rules configured".
+	redactor, err = redact.New(cfg.Redaction)
+	if err != nil {
 		log.SetOutput(os.Stderr)
-		log.Fatalf("Database connection failed: %v", err)
+		log.Fatalf("Invalid redaction configuration: %v", err)
+	}
+
+	// MCP_AUDIT_LOG_PATH enables the structured JSON audit log; it stays off
+	// (a no-op Logger) unless set. MCP_AUDIT_LOG_MAX_BYTES overrides the
+	// rolling file size threshold.
+	auditMaxBytes := int64(audit.DefaultMaxBytes)
+	if bytesStr := os.Getenv("MCP_AUDIT_LOG_MAX_BYTES"); bytesStr != "" {
+		if bytes, convErr := strconv.ParseInt(bytesStr, 10, 64); convErr == nil && bytes > 0 {
+			auditMaxBytes = bytes
+		}
+	}
+	auditLogger, err = audit.NewLogger(os.Getenv("MCP_AUDIT_LOG_PATH"), auditMaxBytes)
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer auditLogger.Close()
+
+	// MCP_MAX_ROWS and MCP_QUERY_TIMEOUT_SECONDS override the mysql_query
+	// safety control defaults; unparsable values fall back to them.
+	if rowsStr := os.Getenv("MCP_MAX_ROWS"); rowsStr != "" {
+		if rows, convErr := strconv.Atoi(rowsStr); convErr == nil && rows > 0 {
+			maxRows = rows
+		}
+	}
+	if bytesStr := os.Getenv("MCP_MAX_BYTES"); bytesStr != "" {
+		if bytes, convErr := strconv.Atoi(bytesStr); convErr == nil && bytes > 0 {
+			maxBytes = bytes
+		}
+	}
+	if timeoutStr := os.Getenv("MCP_QUERY_TIMEOUT_SECONDS"); timeoutStr != "" {
+		if seconds, convErr := time.ParseDuration(timeoutStr + "s"); convErr == nil {
+			queryTimeout = seconds
+		}
+	}
+
+	// MCP_SLOW_QUERY_THRESHOLD_MS overrides the db.DefaultSlowQueryThreshold
+	// used to decide which queries/executes are worth keeping in the rolling
+	// slow-query log surfaced by mysql_health and /metrics.
+	if thresholdStr := os.Getenv("MCP_SLOW_QUERY_THRESHOLD_MS"); thresholdStr != "" {
+		if ms, convErr := strconv.Atoi(thresholdStr); convErr == nil && ms > 0 {
+			db.SlowQueryThreshold = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	// Build the transport for the requested mode and, for HTTP-based
+	// transports, the httpServer that fronts it with auth/CORS middleware
+	// and that graceful shutdown must also stop.
+	mcpTransport, httpServer, err := newTransport(*transportFlag, *addr)
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		log.Fatalf("Transport creation failed: %v", err)
 	}
-	defer db.Close()
 
 	// Create MCP server
 	srv, err := server.NewServer(
-		transport.NewStdioServerTransport(),
+		mcpTransport,
 		server.WithServerInfo(protocol.Implementation{
 			Name:    "mysql-mcp-server",
 			Version: "1.0.0",
@@ -128,6 +293,34 @@ func main() {
 		log.Fatalf("Server creation failed: %v", err)
 	}
 
+	connectionNames := make([]string, 0, len(cfg.Databases))
+	for name := range cfg.Databases {
+		connectionNames = append(connectionNames, name)
+	}
+	sort.Strings(connectionNames)
+
+	connectionProperty := &protocol.Property{
+		Type:        protocol.String,
+		Description: fmt.Sprintf("Named connection to target, as declared in the config file (defaults to %q)", defaultConnection),
+		Enum:        connectionNames,
+	}
+
+	txIDProperty := &protocol.Property{
+		Type:        protocol.String,
+		Description: "Run inside the transaction opened by mysql_begin with this tx_id, instead of auto-committing",
+	}
+
+	encodingProperty := &protocol.Property{
+		Type:        protocol.String,
+		Description: fmt.Sprintf("Row layout: %q (default, one array per row) or %q (one array per column, across all rows)", db.EncodingRow, db.EncodingColumnar),
+		Enum:        []string{db.EncodingRow, db.EncodingColumnar},
+	}
+
+	decimalAsStringProperty := &protocol.Property{
+		Type:        protocol.Boolean,
+		Description: "Return DECIMAL columns as strings instead of numeric JSON, to preserve precision JSON numbers can't represent exactly",
+	}
+
 	// Register query tool
 	srv.RegisterTool(&protocol.Tool{
 		Name:        "mysql_query",
@@ -139,10 +332,65 @@ func main() {
 					Type:        protocol.String,
 					Description: "SQL query statement to execute",
 				},
+				"args": {
+					Type:        protocol.Array,
+					Description: "Positional arguments bound to ? placeholders in the SQL statement",
+				},
+				"named": {
+					Type:        protocol.ObjectT,
+					Description: "Named arguments bound via sql.Named for :name style placeholders",
+				},
+				"connection": connectionProperty,
+				"max_rows": {
+					Type:        protocol.Integer,
+					Description: fmt.Sprintf("Maximum rows to return before truncating (defaults to %d)", maxRows),
+				},
+				"timeout_seconds": {
+					Type:        protocol.Integer,
+					Description: fmt.Sprintf("Query timeout in seconds (defaults to %d)", int(queryTimeout.Seconds())),
+				},
+				"tx_id":             txIDProperty,
+				"encoding":          encodingProperty,
+				"decimal_as_string": decimalAsStringProperty,
+			},
+			Required: []string{"sql"},
+		},
+	}, audited("mysql_query", handleQuery))
+
+	// Register paginated query tool
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_query_page",
+		Description: "Execute a read-only SELECT a page at a time, returning a cursor to fetch the next page",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"sql": {
+					Type:        protocol.String,
+					Description: "SELECT statement to execute; any existing LIMIT/OFFSET is replaced for each page",
+				},
+				"args": {
+					Type:        protocol.Array,
+					Description: "Positional arguments bound to ? placeholders in the SQL statement",
+				},
+				"named": {
+					Type:        protocol.ObjectT,
+					Description: "Named arguments bound via sql.Named for :name style placeholders",
+				},
+				"connection": connectionProperty,
+				"page_size": {
+					Type:        protocol.Integer,
+					Description: fmt.Sprintf("Rows per page (defaults to %d)", maxRows),
+				},
+				"cursor": {
+					Type:        protocol.String,
+					Description: "Opaque cursor returned as next_cursor by a previous call; omit to fetch the first page",
+				},
+				"encoding":          encodingProperty,
+				"decimal_as_string": decimalAsStringProperty,
 			},
 			Required: []string{"sql"},
 		},
-	}, handleQuery)
+	}, audited("mysql_query_page", handleQueryPage))
 
 	// Register execute tool
 	srv.RegisterTool(&protocol.Tool{
@@ -155,18 +403,300 @@ func main() {
 					Type:        protocol.String,
 					Description: "SQL statement to execute",
 				},
+				"args": {
+					Type:        protocol.Array,
+					Description: "Positional arguments bound to ? placeholders in the SQL statement",
+				},
+				"named": {
+					Type:        protocol.ObjectT,
+					Description: "Named arguments bound via sql.Named for :name style placeholders",
+				},
+				"connection": connectionProperty,
+				"tx_id":      txIDProperty,
 			},
 			Required: []string{"sql"},
 		},
-	}, handleExecute)
+	}, audited("mysql_execute", handleExecute))
 
-	// Start server
-	if logEnabled {
-		log.Println("Starting MySQL MCP Server with stdio transport mode")
+	// Register transaction tools
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_begin",
+		Description: "Begin an explicit transaction on a connection and return a tx_id for mysql_query/mysql_execute/mysql_commit/mysql_rollback",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"connection": connectionProperty,
+				"isolation": {
+					Type:        protocol.String,
+					Description: "Isolation level: read_uncommitted, read_committed, repeatable_read, or serializable (defaults to the driver's default)",
+					Enum:        []string{"read_uncommitted", "read_committed", "repeatable_read", "serializable"},
+				},
+			},
+		},
+	}, audited("mysql_begin", handleBegin))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_commit",
+		Description: "Commit the transaction opened by mysql_begin",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"tx_id": {Type: protocol.String, Description: "tx_id returned by mysql_begin"},
+			},
+			Required: []string{"tx_id"},
+		},
+	}, audited("mysql_commit", handleCommit))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_rollback",
+		Description: "Roll back the transaction opened by mysql_begin, or just to a savepoint created by mysql_savepoint if one is given",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"tx_id":     {Type: protocol.String, Description: "tx_id returned by mysql_begin"},
+				"savepoint": {Type: protocol.String, Description: "Name of a savepoint created by mysql_savepoint; rolls back to it instead of ending the transaction"},
+			},
+			Required: []string{"tx_id"},
+		},
+	}, audited("mysql_rollback", handleRollback))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_savepoint",
+		Description: "Create a named savepoint inside the transaction opened by mysql_begin, to later roll back to with mysql_rollback",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"tx_id": {Type: protocol.String, Description: "tx_id returned by mysql_begin"},
+				"name":  {Type: protocol.String, Description: "Savepoint name"},
+			},
+			Required: []string{"tx_id", "name"},
+		},
+	}, audited("mysql_savepoint", handleSavepoint))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_tx_exec",
+		Description: "Run a batch of non-query statements inside the transaction opened by mysql_begin, rolling the whole transaction back on the first failing statement",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"tx_id":      {Type: protocol.String, Description: "tx_id returned by mysql_begin"},
+				"statements": {Type: protocol.Array, Description: "SQL statements to execute in order"},
+			},
+			Required: []string{"tx_id", "statements"},
+		},
+	}, audited("mysql_tx_exec", handleTxExec))
+
+	// Register connection discovery tool
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_list_connections",
+		Description: "List the named database connections configured on this server and their permissions",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+		},
+	}, audited("mysql_list_connections", handleListConnections))
+
+	// Register health/diagnostics tool
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_health",
+		Description: "Report connection pool statistics, reachability, and recent slow queries for each configured connection",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+		},
+	}, audited("mysql_health", handleHealth))
+
+	// Register shard routing explain tool
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_explain_route",
+		Description: "Show how a SQL statement would be routed across shards, without executing it",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"sql": {
+					Type:        protocol.String,
+					Description: "SQL statement to compute a routing plan for",
+				},
+			},
+			Required: []string{"sql"},
+		},
+	}, audited("mysql_explain_route", handleExplainRoute))
+
+	databaseProperty := &protocol.Property{
+		Type:        protocol.String,
+		Description: "Database (schema) name to inspect",
 	}
-	if err = srv.Run(); err != nil {
+
+	// Register schema introspection tools
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_list_databases",
+		Description: "List the databases visible on a connection",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"connection": connectionProperty,
+			},
+		},
+	}, audited("mysql_list_databases", handleListDatabases))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_list_tables",
+		Description: "List the tables in a database, optionally filtered by a LIKE pattern",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"database":   databaseProperty,
+				"pattern":    {Type: protocol.String, Description: "Optional SQL LIKE pattern to filter table names"},
+				"connection": connectionProperty,
+			},
+			Required: []string{"database"},
+		},
+	}, audited("mysql_list_tables", handleListTables))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_describe_table",
+		Description: "Describe a table's columns, types, nullability, keys, and defaults",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"database":   databaseProperty,
+				"table":      {Type: protocol.String, Description: "Table name"},
+				"connection": connectionProperty,
+			},
+			Required: []string{"database", "table"},
+		},
+	}, audited("mysql_describe_table", handleDescribeTable))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_show_indexes",
+		Description: "Show the indexes defined on a table",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"database":   databaseProperty,
+				"table":      {Type: protocol.String, Description: "Table name"},
+				"connection": connectionProperty,
+			},
+			Required: []string{"database", "table"},
+		},
+	}, audited("mysql_show_indexes", handleShowIndexes))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_show_foreign_keys",
+		Description: "Show the foreign key constraints defined on a table",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"database":   databaseProperty,
+				"table":      {Type: protocol.String, Description: "Table name"},
+				"connection": connectionProperty,
+			},
+			Required: []string{"database", "table"},
+		},
+	}, audited("mysql_show_foreign_keys", handleShowForeignKeys))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_show_create",
+		Description: "Show the CREATE TABLE statement that reproduces a table",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"database":   databaseProperty,
+				"table":      {Type: protocol.String, Description: "Table name"},
+				"connection": connectionProperty,
+			},
+			Required: []string{"database", "table"},
+		},
+	}, audited("mysql_show_create", handleShowCreateTable))
+
+	srv.RegisterTool(&protocol.Tool{
+		Name:        "mysql_refresh_schema",
+		Description: "Invalidate cached schema introspection results for a connection, optionally scoped to a database",
+		InputSchema: protocol.InputSchema{
+			Type: protocol.Object,
+			Properties: map[string]*protocol.Property{
+				"database":   databaseProperty,
+				"connection": connectionProperty,
+			},
+		},
+	}, audited("mysql_refresh_schema", handleRefreshSchema))
+
+	// Register the schema:// resource template so clients can resources/read
+	// schema://<connection>/<database>/<table> after discovering it via
+	// resources/templates/list
+	if err := srv.RegisterResourceTemplate(&protocol.ResourceTemplate{
+		Name:        "mysql-table-schema",
+		URITemplate: "schema://{conn}/{db}/{table}",
+		Description: "Columns, indexes, and foreign keys for a MySQL table",
+		MimeType:    "application/json",
+	}, handleSchemaResource); err != nil {
 		log.SetOutput(os.Stderr)
-		log.Fatalf("Service runtime error: %v", err)
+		log.Fatalf("Failed to register schema resource template: %v", err)
+	}
+
+	// Start the HTTP listener, if any, alongside the MCP server's own Run loop
+	if httpServer != nil {
+		go func() {
+			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.SetOutput(os.Stderr)
+				log.Fatalf("HTTP server error: %v", err)
+			}
+		}()
+	}
+
+	// Start the optional /healthz and /metrics listener when metrics_addr is
+	// configured, independent of the transport's own HTTP server.
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsServer = newMetricsServer(cfg.MetricsAddr)
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.SetOutput(os.Stderr)
+				log.Fatalf("Metrics server error: %v", err)
+			}
+		}()
+	}
+
+	runErrCh := make(chan error, 1)
+	go func() {
+		runErrCh <- srv.Run()
+	}()
+
+	if logEnabled {
+		log.Printf("Starting MySQL MCP Server with %s transport mode", *transportFlag)
+	}
+
+	// Shut down cleanly on SIGINT/SIGTERM instead of letting in-flight tool
+	// calls and the HTTP listener be killed mid-request.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case sig := <-sigCh:
+		if logEnabled {
+			log.Printf("Received signal %v, shutting down", sig)
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.SetOutput(os.Stderr)
+			log.Printf("MCP server shutdown error: %v", err)
+		}
+		if httpServer != nil {
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				log.SetOutput(os.Stderr)
+				log.Printf("HTTP server shutdown error: %v", err)
+			}
+		}
+		if metricsServer != nil {
+			if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+				log.SetOutput(os.Stderr)
+				log.Printf("Metrics server shutdown error: %v", err)
+			}
+		}
+	case err := <-runErrCh:
+		if err != nil {
+			log.SetOutput(os.Stderr)
+			log.Fatalf("Service runtime error: %v", err)
+		}
 	}
 }
 
@@ -186,100 +716,739 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return value
 }
 
-// Initialize database connection
-func initDB(connectionDSN string) error {
-	var err error
-	db, err = sql.Open("mysql", connectionDSN)
-	if err != nil {
-		return err
+// stringArg extracts a string tool argument, returning an error if it is
+// present but not a string; missing arguments yield the empty string.
+func stringArg(arguments map[string]interface{}, name string) (string, error) {
+	raw, ok := arguments[name]
+	if !ok || raw == nil {
+		return "", nil
 	}
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", name)
+	}
+	return value, nil
+}
 
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(60) // 1 minute
-
-	return db.Ping()
+// intArg extracts an integer tool argument, returning defaultValue when it is
+// absent. JSON numbers arrive as float64, so non-integral values are rejected.
+func intArg(arguments map[string]interface{}, name string, defaultValue int) (int, error) {
+	raw, ok := arguments[name]
+	if !ok || raw == nil {
+		return defaultValue, nil
+	}
+	value, ok := raw.(float64)
+	if !ok || value != float64(int(value)) {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return int(value), nil
 }
 
-// Handle MySQL query requests
-func handleQuery(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
-	startTime := time.Now()
-	sql, ok := request.Arguments["sql"].(string)
+// boolArg extracts a boolean tool argument, returning defaultValue when it is
+// absent.
+func boolArg(arguments map[string]interface{}, name string, defaultValue bool) (bool, error) {
+	raw, ok := arguments[name]
+	if !ok || raw == nil {
+		return defaultValue, nil
+	}
+	value, ok := raw.(bool)
 	if !ok {
-		return nil, errors.New("sql must be a string")
+		return false, fmt.Errorf("%s must be a boolean", name)
 	}
+	return value, nil
+}
 
-	if logEnabled {
-		log.Printf("[QUERY REQUEST] SQL: %s", sql)
+// encodingArg extracts the optional "encoding" tool argument (db.EncodingRow
+// or db.EncodingColumnar), defaulting to db.EncodingRow.
+func encodingArg(arguments map[string]interface{}) (string, error) {
+	encoding, err := stringArg(arguments, "encoding")
+	if err != nil {
+		return "", err
 	}
-
-	// Ensure it's a read-only query
-	sqlUpper := strings.TrimSpace(strings.ToUpper(sql))
-	if !strings.HasPrefix(sqlUpper, "SELECT") && !strings.HasPrefix(sqlUpper, "SHOW") && !strings.HasPrefix(sqlUpper, "DESCRIBE") {
-		if logEnabled {
-			log.Printf("[QUERY REJECTED] Invalid query type: %s", sqlUpper[:10])
-		}
-		return nil, errors.New("only SELECT, SHOW, or DESCRIBE queries are allowed")
+	switch encoding {
+	case "", db.EncodingRow:
+		return db.EncodingRow, nil
+	case db.EncodingColumnar:
+		return db.EncodingColumnar, nil
+	default:
+		return "", fmt.Errorf("encoding must be %q or %q", db.EncodingRow, db.EncodingColumnar)
 	}
+}
 
-	// Execute query
-	rows, err := db.Query(sql)
+// requiredStringArg is like stringArg but rejects a missing or empty value.
+func requiredStringArg(arguments map[string]interface{}, name string) (string, error) {
+	value, err := stringArg(arguments, name)
 	if err != nil {
-		if logEnabled {
-			log.Printf("[QUERY ERROR] %v", err)
-		}
-		return nil, fmt.Errorf("query execution error: %v", err)
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("%s is required", name)
 	}
-	defer rows.Close()
+	return value, nil
+}
 
-	// Get column names
-	columns, err := rows.Columns()
+func textResult(value interface{}) (*protocol.CallToolResult, error) {
+	jsonData, err := json.Marshal(value)
 	if err != nil {
-		if logEnabled {
-			log.Printf("[QUERY ERROR] Failed to get column names: %v", err)
-		}
-		return nil, fmt.Errorf("failed to get column names: %v", err)
+		return nil, fmt.Errorf("JSON serialization failed: %v", err)
 	}
+	return &protocol.CallToolResult{
+		Content: []protocol.Content{
+			protocol.TextContent{Type: "text", Text: string(jsonData)},
+		},
+	}, nil
+}
 
-	// Process results
-	var results []map[string]interface{}
-	values := make([]interface{}, len(columns))
-	scanArgs := make([]interface{}, len(columns))
-	for i := range values {
-		scanArgs[i] = &values[i]
+// Handle database discovery requests
+func handleListDatabases(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
 	}
 
-	rowCount := 0
-	for rows.Next() {
-		rowCount++
-		if err = rows.Scan(scanArgs...); err != nil {
-			if logEnabled {
-				log.Printf("[QUERY ERROR] Failed to read row data: %v", err)
-			}
-			return nil, fmt.Errorf("failed to read row data: %v", err)
-		}
-
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
-			}
-		}
-		results = append(results, row)
+	databases, err := schemaCache.ListDatabases(conn)
+	if err != nil {
+		return nil, fmt.Errorf("list databases error: %v", err)
 	}
+	return textResult(map[string]interface{}{"databases": databases})
+}
 
-	if err = rows.Err(); err != nil {
-		if logEnabled {
-			log.Printf("[QUERY ERROR] Error iterating through results: %v", err)
-		}
-		return nil, fmt.Errorf("error iterating through results: %v", err)
+// Handle table discovery requests
+func handleListTables(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	database, err := requiredStringArg(request.Arguments, "database")
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := stringArg(request.Arguments, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := schemaCache.ListTables(conn, database, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("list tables error: %v", err)
+	}
+	return textResult(map[string]interface{}{"tables": tables})
+}
+
+// Handle table description requests
+func handleDescribeTable(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	database, err := requiredStringArg(request.Arguments, "database")
+	if err != nil {
+		return nil, err
+	}
+	table, err := requiredStringArg(request.Arguments, "table")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := schemaCache.DescribeTable(conn, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("describe table error: %v", err)
+	}
+	return textResult(map[string]interface{}{"columns": columns})
+}
+
+// Handle index listing requests
+func handleShowIndexes(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	database, err := requiredStringArg(request.Arguments, "database")
+	if err != nil {
+		return nil, err
+	}
+	table, err := requiredStringArg(request.Arguments, "table")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := schemaCache.ShowIndexes(conn, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("show indexes error: %v", err)
+	}
+	return textResult(map[string]interface{}{"indexes": indexes})
+}
+
+// Handle foreign key listing requests
+func handleShowForeignKeys(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	database, err := requiredStringArg(request.Arguments, "database")
+	if err != nil {
+		return nil, err
+	}
+	table, err := requiredStringArg(request.Arguments, "table")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	foreignKeys, err := schemaCache.ShowForeignKeys(conn, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("show foreign keys error: %v", err)
+	}
+	return textResult(map[string]interface{}{"foreign_keys": foreignKeys})
+}
+
+// Handle CREATE TABLE statement requests
+func handleShowCreateTable(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	database, err := requiredStringArg(request.Arguments, "database")
+	if err != nil {
+		return nil, err
+	}
+	table, err := requiredStringArg(request.Arguments, "table")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	createTable, err := schemaCache.ShowCreateTable(conn, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("show create table error: %v", err)
+	}
+	return textResult(createTable)
+}
+
+// Handle schema cache invalidation requests. Invalidation is scoped to the
+// connection and, if given, the database; pkg/schema.Cache keys individual
+// objects as "<kind>:<table>", so a bare table name cannot be matched
+// directly and the whole database's cache is dropped instead.
+func handleRefreshSchema(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	database, err := stringArg(request.Arguments, "database")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaCache.Invalidate(conn, database, "")
+
+	return textResult(map[string]interface{}{"refreshed": true, "connection": conn, "database": database})
+}
+
+// Handle schema:// resource reads, returning a table's columns, indexes, and
+// foreign keys as a single JSON document
+func handleSchemaResource(request *protocol.ReadResourceRequest) (*protocol.ReadResourceResult, error) {
+	conn, _ := request.Arguments["conn"].(string)
+	database, _ := request.Arguments["db"].(string)
+	table, _ := request.Arguments["table"].(string)
+	if conn == "" || database == "" || table == "" {
+		return nil, fmt.Errorf("invalid schema resource URI: %s", request.URI)
+	}
+
+	columns, err := schemaCache.DescribeTable(conn, database, table)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := schemaCache.ShowIndexes(conn, database, table)
+	if err != nil {
+		return nil, err
+	}
+	foreignKeys, err := schemaCache.ShowForeignKeys(conn, database, table)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"connection":   conn,
+		"database":     database,
+		"table":        table,
+		"columns":      columns,
+		"indexes":      indexes,
+		"foreign_keys": foreignKeys,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("JSON serialization failed: %v", err)
+	}
+
+	return &protocol.ReadResourceResult{
+		Contents: []protocol.ResourceContents{
+			protocol.TextResourceContents{
+				URI:      request.URI,
+				MimeType: "application/json",
+				Text:     string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// newTransport builds the go-mcp transport for the requested mode. For
+// "http+sse" it also returns the *http.Server fronting it, wrapped with bearer
+// auth and CORS middleware, so the caller can drive its graceful shutdown
+// alongside the MCP server's; for "stdio" httpServer is nil.
+func newTransport(mode, addr string) (transport.ServerTransport, *http.Server, error) {
+	switch mode {
+	case "stdio":
+		return transport.NewStdioServerTransport(), nil, nil
+
+	case "http+sse":
+		// NewSSEServerTransportAndHandler (rather than NewSSEServerTransport)
+		// hands back bare handlers instead of starting its own listener, so
+		// the auth/CORS middleware below and graceful shutdown can wrap a
+		// single http.Server we control.
+		sseTransport, handler, err := transport.NewSSEServerTransportAndHandler("/messages")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create SSE transport: %v", err)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/sse", withMiddleware(handler.HandleSSE()))
+		mux.Handle("/messages", withMiddleware(handler.HandleMessage()))
+
+		httpServer := &http.Server{
+			Addr:    addr,
+			Handler: mux,
+		}
+		return sseTransport, httpServer, nil
+
+	case "websocket":
+		// go-mcp v0.1.14's transport.ServerTransport interface requires
+		// SetSessionManager to accept its package-private sessionManager
+		// type (transport.go:55/79), which an external package cannot even
+		// name, let alone implement a conforming method signature for —
+		// this isn't a missing convenience wrapper, it's a hard compile-time
+		// wall against implementing ServerTransport outside the go-mcp
+		// module itself. Rather than faking a websocket listener that can't
+		// actually plug into the MCP session lifecycle, report this mode as
+		// unsupported; stdio + http+sse is the accepted transport scope
+		// until go-mcp exposes a public extension point (or ships its own
+		// websocket transport).
+		return nil, nil, errors.New("websocket transport is not supported by go-mcp v0.1.14; use stdio or http+sse")
+
+	default:
+		return nil, nil, fmt.Errorf("unknown transport %q (expected stdio or http+sse)", mode)
+	}
+}
+
+// newMetricsServer builds the optional /healthz and /metrics HTTP listener
+// enabled by setting metrics_addr in the config file. It is independent of
+// the http+sse transport's own *http.Server and runs alongside it (or
+// alongside the stdio transport) whenever configured.
+func newMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/metrics", handleMetrics)
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+// handleHealthz reports 200 when every configured connection answers a ping
+// within queryTimeout, and 503 otherwise, for use as a liveness/readiness probe.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+	defer cancel()
+
+	stats := dbManager.Stats(ctx)
+	healthy := true
+	for _, s := range stats {
+		if s.PingError != "" {
+			healthy = false
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":          healthy,
+		"connections": stats,
+	})
+}
+
+// handleMetrics exposes connection pool statistics in Prometheus text
+// exposition format, labeled by connection name.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+	defer cancel()
+
+	stats := dbManager.Stats(ctx)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP ai2mysql_db_open_connections Open connections for the connection pool.")
+	fmt.Fprintln(w, "# TYPE ai2mysql_db_open_connections gauge")
+	for name, s := range stats {
+		fmt.Fprintf(w, "ai2mysql_db_open_connections{connection=%q} %d\n", name, s.OpenConnections)
+	}
+
+	fmt.Fprintln(w, "# HELP ai2mysql_db_in_use Connections currently in use.")
+	fmt.Fprintln(w, "# TYPE ai2mysql_db_in_use gauge")
+	for name, s := range stats {
+		fmt.Fprintf(w, "ai2mysql_db_in_use{connection=%q} %d\n", name, s.InUse)
+	}
+
+	fmt.Fprintln(w, "# HELP ai2mysql_db_idle Idle connections in the pool.")
+	fmt.Fprintln(w, "# TYPE ai2mysql_db_idle gauge")
+	for name, s := range stats {
+		fmt.Fprintf(w, "ai2mysql_db_idle{connection=%q} %d\n", name, s.Idle)
+	}
+
+	fmt.Fprintln(w, "# HELP ai2mysql_db_up Whether the last ping to the connection succeeded.")
+	fmt.Fprintln(w, "# TYPE ai2mysql_db_up gauge")
+	for name, s := range stats {
+		up := 1
+		if s.PingError != "" {
+			up = 0
+		}
+		fmt.Fprintf(w, "ai2mysql_db_up{connection=%q} %d\n", name, up)
+	}
+
+	fmt.Fprintln(w, "# HELP ai2mysql_slow_queries_total Number of slow queries currently retained in the rolling log.")
+	fmt.Fprintln(w, "# TYPE ai2mysql_slow_queries_total gauge")
+	fmt.Fprintf(w, "ai2mysql_slow_queries_total %d\n", len(dbManager.SlowQueries()))
+}
+
+// withMiddleware applies bearer-token auth (when MCP_AUTH_TOKEN is set) and
+// CORS headers (controlled by MCP_CORS_ORIGIN, default "*") to an HTTP
+// transport handler.
+func withMiddleware(next http.Handler) http.Handler {
+	return withCORS(withAuth(next))
+}
+
+// withAuth rejects requests missing a matching "Authorization: Bearer <token>"
+// header when MCP_AUTH_TOKEN is configured; it is a no-op otherwise.
+func withAuth(next http.Handler) http.Handler {
+	token := os.Getenv("MCP_AUTH_TOKEN")
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withCORS sets Access-Control-* response headers for browser-based clients
+// and short-circuits preflight OPTIONS requests.
+func withCORS(next http.Handler) http.Handler {
+	origin := getEnvWithDefault("MCP_CORS_ORIGIN", "*")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseBoundArgs extracts the optional "args" (positional ? placeholders) and
+// "named" (sql.Named placeholders) tool arguments and merges them into a
+// single slice suitable for database/sql's variadic args.
+func parseBoundArgs(arguments map[string]interface{}) ([]interface{}, error) {
+	var positional []interface{}
+	if raw, ok := arguments["args"]; ok && raw != nil {
+		list, ok := raw.([]interface{})
+		if !ok {
+			return nil, errors.New("args must be an array")
+		}
+		positional = list
+	}
+
+	var named map[string]interface{}
+	if raw, ok := arguments["named"]; ok && raw != nil {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("named must be an object")
+		}
+		named = m
+	}
+
+	return db.BuildArgs(positional, named), nil
+}
+
+// connectionName extracts the optional "connection" tool argument, defaulting
+// to defaultConnection when it is not provided.
+func connectionName(arguments map[string]interface{}) (string, error) {
+	raw, ok := arguments["connection"]
+	if !ok || raw == nil {
+		return defaultConnection, nil
+	}
+	name, ok := raw.(string)
+	if !ok {
+		return "", errors.New("connection must be a string")
+	}
+	if name == "" {
+		return defaultConnection, nil
+	}
+	return name, nil
+}
+
+// checkTablePermission rejects a statement if any table it references is
+// blocked by the target connection's allowed_tables/denied_tables lists.
+func checkTablePermission(conn string, stmt sqlparser.Statement) error {
+	permission := dbManager.Permission(conn)
+	for _, table := range sqlsafety.TablesReferenced(stmt) {
+		if !permission.TableAllowed(table) {
+			return fmt.Errorf("连接 %s 不允许访问表 %s", conn, table)
+		}
+	}
+	return nil
+}
+
+// rejectUnsafeStar rejects a "SELECT *" (or "t.*") when any table it
+// references has a column matching the redaction rules, unless the target
+// connection's permission sets allow_raw_pii. Queries that name their
+// columns explicitly bypass this check entirely, since the caller already
+// said exactly what they want back.
+func rejectUnsafeStar(conn string, stmt sqlparser.Statement) error {
+	if !sqlsafety.SelectsStar(stmt) || dbManager.Permission(conn).AllowRawPII {
+		return nil
+	}
+
+	dbName, err := dbManager.DatabaseName(conn)
+	if err != nil || dbName == "" {
+		// No schema to introspect against (e.g. an unconfigured connection
+		// will already have failed elsewhere); nothing to check here.
+		return nil
+	}
+
+	for _, table := range sqlsafety.TablesReferenced(stmt) {
+		columns, err := schemaCache.DescribeTable(conn, dbName, table)
+		if err != nil {
+			// A schema lookup failure shouldn't itself block an otherwise
+			// legitimate query; it will fail for its own reasons if the
+			// table doesn't exist.
+			continue
+		}
+		for _, col := range columns {
+			if redactor.ColumnSensitive(col.Name) {
+				return fmt.Errorf("SELECT * from %s would expose redacted column %q; select explicit columns or set allow_raw_pii for this connection", table, col.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// auditResultShape best-effort extracts a row count and the result-level
+// "redacted" flag from a tool's JSON response for the audit log, without
+// each handler having to report them explicitly. It recognizes the
+// "rows"/"rows_affected"/"redacted" shapes used by the query/execute tools
+// and is a no-op (0, false) for every other tool's response.
+func auditResultShape(result *protocol.CallToolResult) (rowCount int, redacted bool) {
+	if result == nil || len(result.Content) == 0 {
+		return 0, false
+	}
+	text, ok := result.Content[0].(protocol.TextContent)
+	if !ok {
+		return 0, false
+	}
+
+	var shape struct {
+		Rows              json.RawMessage `json:"rows"`
+		Columnar          bool            `json:"columnar"`
+		RowsAffected      *int64          `json:"rows_affected"`
+		RowsAffectedCamel *int64          `json:"rowsAffected"`
+		Redacted          bool            `json:"redacted"`
+	}
+	if err := json.Unmarshal([]byte(text.Text), &shape); err != nil {
+		return 0, false
+	}
+	switch {
+	case shape.RowsAffected != nil:
+		rowCount = int(*shape.RowsAffected)
+	case shape.RowsAffectedCamel != nil:
+		rowCount = int(*shape.RowsAffectedCamel)
+	case len(shape.Rows) > 0:
+		var rows []json.RawMessage
+		if err := json.Unmarshal(shape.Rows, &rows); err == nil {
+			rowCount = len(rows)
+			// A columnar result holds one array per column, so the row
+			// count is the length of a column, not of "rows".
+			if shape.Columnar {
+				rowCount = 0
+				var column []json.RawMessage
+				if len(rows) > 0 && json.Unmarshal(rows[0], &column) == nil {
+					rowCount = len(column)
+				}
+			}
+		}
+	}
+	return rowCount, shape.Redacted
+}
+
+// audited wraps a tool handler so every call through it is recorded to
+// auditLogger (a no-op when audit logging is disabled) with its target
+// connection, redacted SQL text, argument hash, row count, duration, and any
+// error - regardless of which tool handled the request. go-mcp v0.1.14's
+// ToolHandlerFunc does not carry per-call session/client identity, so
+// Record.CallerID stays empty until a future go-mcp version exposes it.
+func audited(tool string, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+		start := time.Now()
+		result, err := h(request)
+
+		rec := audit.Record{
+			Time:       time.Now(),
+			Tool:       tool,
+			ArgsHash:   audit.HashArgs(request.Arguments),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if conn, ok := request.Arguments["connection"].(string); ok && conn != "" {
+			rec.Connection = conn
+		}
+		if sql, ok := request.Arguments["sql"].(string); ok {
+			masked := redactor.MaskSQL(sql)
+			rec.SQL = masked
+			rec.Redacted = masked != sql
+		}
+		if err != nil {
+			rec.Error = err.Error()
+		} else {
+			rowCount, resultRedacted := auditResultShape(result)
+			rec.RowCount = rowCount
+			// Result-level redaction (row values masked by MaskRows/MaskColumnar)
+			// is distinct from SQL-text redaction above; a query whose SQL text
+			// is untouched can still have masked result columns, so OR the two
+			// rather than letting the result's "redacted" field overwrite it.
+			rec.Redacted = rec.Redacted || resultRedacted
+		}
+		auditLogger.Log(rec)
+
+		return result, err
+	}
+}
+
+// Handle MySQL query requests
+func handleQuery(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	startTime := time.Now()
+	sql, ok := request.Arguments["sql"].(string)
+	if !ok {
+		return nil, errors.New("sql must be a string")
+	}
+
+	boundArgs, err := parseBoundArgs(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	rowLimit, err := intArg(request.Arguments, "max_rows", maxRows)
+	if err != nil {
+		return nil, err
+	}
+	timeoutSeconds, err := intArg(request.Arguments, "timeout_seconds", int(queryTimeout.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	txID, err := stringArg(request.Arguments, "tx_id")
+	if err != nil {
+		return nil, err
+	}
+	encoding, err := encodingArg(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	decimalAsString, err := boolArg(request.Arguments, "decimal_as_string", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if logEnabled {
+		log.Printf("[QUERY REQUEST] connection: %s, SQL: %s, args: %v", conn, sql, boundArgs)
+	}
+
+	// Parse the statement and reject anything that isn't read-only; unlike a
+	// string prefix check this also rejects DML smuggled through comments or
+	// (where the parser supports it) subqueries.
+	stmt, err := sqlsafety.CheckReadOnly(sql)
+	if err != nil {
+		if logEnabled {
+			log.Printf("[QUERY REJECTED] %v", err)
+		}
+		return nil, err
+	}
+	if err := checkTablePermission(conn, stmt); err != nil {
+		if logEnabled {
+			log.Printf("[QUERY REJECTED] %v", err)
+		}
+		return nil, err
+	}
+	if err := rejectUnsafeStar(conn, stmt); err != nil {
+		if logEnabled {
+			log.Printf("[QUERY REJECTED] %v", err)
+		}
+		return nil, err
+	}
+
+	// LIMIT-less SELECTs get "LIMIT rowLimit+1" appended so a truncated result
+	// can be detected below without a second round trip.
+	sql = sqlsafety.ApplyLimit(stmt, rowLimit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	// A tx_id runs the query inside the transaction opened by mysql_begin,
+	// on whichever connection it was started on. Otherwise tables with a
+	// configured shard rule are routed across connections by sqlRouter, and
+	// everything else goes to the single requested connection pool.
+	// MaxRows is rowLimit+1, matching the "LIMIT rowLimit+1" ApplyLimit just
+	// injected, so the scan-time cap doesn't itself swallow the extra row
+	// sqlsafety.Truncate below needs to detect truncation.
+	queryOpts := db.QueryOptions{MaxRows: rowLimit + 1, MaxBytes: maxBytes, DecimalAsString: decimalAsString, Encoding: encoding}
+
+	var result *db.QueryResult
+	switch {
+	case txID != "":
+		result, err = dbManager.QueryTxWithOptions(txID, queryOpts, sql, boundArgs...)
+	case sqlRouter.HasRule(router.ExtractTable(sql)):
+		result, _, err = sqlRouter.Query(sql, boundArgs...)
+	default:
+		result, err = dbManager.QueryContextWithOptions(ctx, conn, sql, queryOpts, boundArgs...)
+	}
+	if err != nil {
+		if logEnabled {
+			log.Printf("[QUERY ERROR] %v", err)
+		}
+		return nil, fmt.Errorf("query execution error: %v", err)
+	}
+	// Every path can hand back more than rowLimit rows: queryOpts allows
+	// rowLimit+1, and the shard-router path queries each shard with the db
+	// package defaults rather than queryOpts. The final pass below cuts the
+	// result to rowLimit rows, keeping any truncation runQuery already flagged
+	// (e.g. on MaxBytes). sqlsafety.Truncate works on row-major data; a
+	// columnar result holds one array per column, so each column is cut instead.
+	if !result.Columnar {
+		var truncated bool
+		result.Rows, truncated = sqlsafety.Truncate(result.Rows, rowLimit)
+		result.Truncated = result.Truncated || truncated
+		result.Redacted = redactor.MaskRows(result.Columns, result.Rows)
+	} else {
+		if len(result.Rows) > 0 && len(result.Rows[0]) > rowLimit {
+			for c := range result.Rows {
+				result.Rows[c] = result.Rows[c][:rowLimit]
+			}
+			result.Truncated = true
+		}
+		result.Redacted = redactor.MaskColumnar(result.Columns, result.Rows)
 	}
 
 	// Convert to JSON
-	jsonData, err := json.Marshal(results)
+	jsonData, err := json.Marshal(result)
 	if err != nil {
 		if logEnabled {
 			log.Printf("[QUERY ERROR] JSON serialization failed: %v", err)
@@ -289,7 +1458,7 @@ func handleQuery(request *protocol.CallToolRequest) (*protocol.CallToolResult, e
 
 	executionTime := time.Since(startTime)
 	if logEnabled {
-		log.Printf("[QUERY COMPLETED] Time: %v, Rows: %d, SQL: %s", executionTime, rowCount, sql)
+		log.Printf("[QUERY COMPLETED] connection: %s, Time: %v, Rows: %d, SQL: %s", conn, executionTime, len(result.Rows), sql)
 	}
 
 	return &protocol.CallToolResult{
@@ -302,6 +1471,135 @@ func handleQuery(request *protocol.CallToolRequest) (*protocol.CallToolResult, e
 	}, nil
 }
 
+// Handle paginated MySQL query requests. The cursor is an opaque base64
+// encoding of the OFFSET to resume from; it is not a keyset cursor, so pages
+// can shift if rows are inserted/deleted between calls on the same SQL.
+func handleQueryPage(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	sql, ok := request.Arguments["sql"].(string)
+	if !ok {
+		return nil, errors.New("sql must be a string")
+	}
+
+	boundArgs, err := parseBoundArgs(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	pageSize, err := intArg(request.Arguments, "page_size", maxRows)
+	if err != nil {
+		return nil, err
+	}
+	if pageSize <= 0 {
+		return nil, errors.New("page_size must be a positive integer")
+	}
+	cursor, err := stringArg(request.Arguments, "cursor")
+	if err != nil {
+		return nil, err
+	}
+	offset, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	encoding, err := encodingArg(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	decimalAsString, err := boolArg(request.Arguments, "decimal_as_string", false)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := sqlsafety.CheckReadOnly(sql)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTablePermission(conn, stmt); err != nil {
+		return nil, err
+	}
+	if err := rejectUnsafeStar(conn, stmt); err != nil {
+		return nil, err
+	}
+
+	pagedSQL, err := sqlsafety.ApplyPage(stmt, pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	queryOpts := db.QueryOptions{MaxRows: pageSize + 1, MaxBytes: maxBytes, DecimalAsString: decimalAsString, Encoding: encoding}
+	result, err := dbManager.QueryContextWithOptions(ctx, conn, pagedSQL, queryOpts, boundArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution error: %v", err)
+	}
+
+	// ApplyPage asked for pageSize+1 rows; a full page means there is at
+	// least one more row beyond it. Columnar results are transposed (one
+	// array per column, all the same length), so the row count has to be
+	// read off a column instead of len(Rows).
+	rowCount := len(result.Rows)
+	if result.Columnar {
+		rowCount = 0
+		if len(result.Rows) > 0 {
+			rowCount = len(result.Rows[0])
+		}
+	}
+	if rowCount > pageSize {
+		if result.Columnar {
+			for c := range result.Rows {
+				result.Rows[c] = result.Rows[c][:pageSize]
+			}
+		} else {
+			result.Rows = result.Rows[:pageSize]
+		}
+		result.NextCursor = encodeCursor(offset + pageSize)
+	} else if result.Truncated {
+		// runQuery cut the scan short on MaxBytes before it ever reached
+		// pageSize+1 rows, so rowCount <= pageSize rows came back even though
+		// more rows exist beyond them. Resume right after what we actually
+		// returned (offset+rowCount), not offset+pageSize — the latter would
+		// silently skip the rows between the byte cutoff and the page
+		// boundary on the next call.
+		result.NextCursor = encodeCursor(offset + rowCount)
+	}
+	result.Truncated = result.NextCursor != ""
+
+	if result.Columnar {
+		result.Redacted = redactor.MaskColumnar(result.Columns, result.Rows)
+	} else {
+		result.Redacted = redactor.MaskRows(result.Columns, result.Rows)
+	}
+
+	return textResult(result)
+}
+
+// encodeCursor turns a row offset into the opaque cursor token handed back as
+// next_cursor
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodeCursor recovers the row offset from a cursor token; an empty cursor
+// (the first page) decodes to offset 0
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("cursor does not encode a valid offset")
+	}
+	return offset, nil
+}
+
 // Handle MySQL execute requests
 func handleExecute(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
 	startTime := time.Now()
@@ -310,47 +1608,55 @@ func handleExecute(request *protocol.CallToolRequest) (*protocol.CallToolResult,
 		return nil, errors.New("sql must be a string")
 	}
 
-	if logEnabled {
-		log.Printf("[EXECUTE REQUEST] SQL: %s", sql)
+	boundArgs, err := parseBoundArgs(request.Arguments)
+	if err != nil {
+		return nil, err
 	}
 
-	// Permission check
-	sqlUpper := strings.TrimSpace(strings.ToUpper(sql))
-
-	// Check INSERT permission
-	if strings.HasPrefix(sqlUpper, "INSERT") && !allowInsert {
-		if logEnabled {
-			log.Printf("[EXECUTE REJECTED] No INSERT permission")
-		}
-		return nil, errors.New("no INSERT permission")
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	txID, err := stringArg(request.Arguments, "tx_id")
+	if err != nil {
+		return nil, err
 	}
 
-	// Check UPDATE permission
-	if strings.HasPrefix(sqlUpper, "UPDATE") && !allowUpdate {
-		if logEnabled {
-			log.Printf("[EXECUTE REJECTED] No UPDATE permission")
-		}
-		return nil, errors.New("no UPDATE permission")
+	if logEnabled {
+		log.Printf("[EXECUTE REQUEST] connection: %s, SQL: %s, args: %v", conn, sql, boundArgs)
 	}
 
-	// Check DELETE permission
-	if strings.HasPrefix(sqlUpper, "DELETE") && !allowDelete {
+	// Prohibit dangerous operations regardless of the target connection's
+	// permissions. This parses the statement instead of matching string
+	// prefixes, so DROP/TRUNCATE/ALTER/SET GLOBAL can't be smuggled past it.
+	stmt, err := sqlsafety.CheckWriteAllowed(sql)
+	if err != nil {
 		if logEnabled {
-			log.Printf("[EXECUTE REJECTED] No DELETE permission")
+			log.Printf("[EXECUTE REJECTED] %v", err)
 		}
-		return nil, errors.New("no DELETE permission")
+		return nil, err
 	}
-
-	// Prohibit dangerous operations
-	if strings.HasPrefix(sqlUpper, "DROP") || strings.HasPrefix(sqlUpper, "TRUNCATE") {
+	if err := checkTablePermission(conn, stmt); err != nil {
 		if logEnabled {
-			log.Printf("[EXECUTE REJECTED] Dangerous operation not allowed: %s", sqlUpper[:10])
+			log.Printf("[EXECUTE REJECTED] %v", err)
 		}
-		return nil, errors.New("DROP or TRUNCATE operations are not allowed")
+		return nil, err
 	}
 
-	// Execute SQL
-	result, err := db.Exec(sql)
+	// A tx_id runs the statement inside the transaction opened by
+	// mysql_begin. Otherwise it executes through the requested connection
+	// pool (permission checks for INSERT/UPDATE/DELETE are enforced
+	// per-connection by dbManager.Execute), or across shards via sqlRouter
+	// for tables with a configured shard rule.
+	var result *db.ExecuteResult
+	switch {
+	case txID != "":
+		result, err = dbManager.ExecuteTx(txID, sql, boundArgs...)
+	case sqlRouter.HasRule(router.ExtractTable(sql)):
+		result, _, err = sqlRouter.Execute(sql, boundArgs...)
+	default:
+		result, err = dbManager.Execute(conn, sql, boundArgs...)
+	}
 	if err != nil {
 		if logEnabled {
 			log.Printf("[EXECUTE ERROR] %v", err)
@@ -358,13 +1664,9 @@ func handleExecute(request *protocol.CallToolRequest) (*protocol.CallToolResult,
 		return nil, fmt.Errorf("SQL execution error: %v", err)
 	}
 
-	// Get results
-	lastInsertID, _ := result.LastInsertId()
-	rowsAffected, _ := result.RowsAffected()
-
 	response := map[string]interface{}{
-		"lastInsertId": lastInsertID,
-		"rowsAffected": rowsAffected,
+		"lastInsertId": result.LastInsertID,
+		"rowsAffected": result.RowsAffected,
 	}
 
 	jsonData, err := json.Marshal(response)
@@ -377,8 +1679,204 @@ func handleExecute(request *protocol.CallToolRequest) (*protocol.CallToolResult,
 
 	executionTime := time.Since(startTime)
 	if logEnabled {
-		log.Printf("[EXECUTE COMPLETED] Time: %v, Rows affected: %d, Last insert ID: %d, SQL: %s",
-			executionTime, rowsAffected, lastInsertID, sql)
+		log.Printf("[EXECUTE COMPLETED] connection: %s, Time: %v, Rows affected: %d, Last insert ID: %d, SQL: %s",
+			conn, executionTime, result.RowsAffected, result.LastInsertID, sql)
+	}
+
+	return &protocol.CallToolResult{
+		Content: []protocol.Content{
+			protocol.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// isolationLevels maps the mysql_begin "isolation" argument to the
+// corresponding sql.IsolationLevel constant
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read_uncommitted": sql.LevelReadUncommitted,
+	"read_committed":   sql.LevelReadCommitted,
+	"repeatable_read":  sql.LevelRepeatableRead,
+	"serializable":     sql.LevelSerializable,
+}
+
+// Handle transaction begin requests
+func handleBegin(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	conn, err := connectionName(request.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	isolation := sql.LevelDefault
+	if raw, err := stringArg(request.Arguments, "isolation"); err != nil {
+		return nil, err
+	} else if raw != "" {
+		level, ok := isolationLevels[raw]
+		if !ok {
+			return nil, fmt.Errorf("unknown isolation level %q", raw)
+		}
+		isolation = level
+	}
+
+	txID, err := dbManager.BeginTx(conn, isolation)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction error: %v", err)
+	}
+
+	return textResult(map[string]interface{}{"tx_id": txID, "connection": conn})
+}
+
+// Handle transaction commit requests
+func handleCommit(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	txID, err := requiredStringArg(request.Arguments, "tx_id")
+	if err != nil {
+		return nil, err
+	}
+	if err := dbManager.CommitTx(txID); err != nil {
+		return nil, fmt.Errorf("commit transaction error: %v", err)
+	}
+	return textResult(map[string]interface{}{"tx_id": txID, "committed": true})
+}
+
+// Handle transaction rollback requests. A "savepoint" argument rolls back to
+// that savepoint only, leaving the transaction open; otherwise the whole
+// transaction ends.
+func handleRollback(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	txID, err := requiredStringArg(request.Arguments, "tx_id")
+	if err != nil {
+		return nil, err
+	}
+	savepoint, err := stringArg(request.Arguments, "savepoint")
+	if err != nil {
+		return nil, err
+	}
+
+	if savepoint != "" {
+		if err := dbManager.RollbackToSavepoint(txID, savepoint); err != nil {
+			return nil, fmt.Errorf("rollback to savepoint error: %v", err)
+		}
+		return textResult(map[string]interface{}{"tx_id": txID, "savepoint": savepoint, "rolled_back": true})
+	}
+
+	if err := dbManager.RollbackTx(txID); err != nil {
+		return nil, fmt.Errorf("rollback transaction error: %v", err)
+	}
+	return textResult(map[string]interface{}{"tx_id": txID, "rolled_back": true})
+}
+
+// Handle savepoint creation requests
+func handleSavepoint(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	txID, err := requiredStringArg(request.Arguments, "tx_id")
+	if err != nil {
+		return nil, err
+	}
+	name, err := requiredStringArg(request.Arguments, "name")
+	if err != nil {
+		return nil, err
+	}
+	if err := dbManager.Savepoint(txID, name); err != nil {
+		return nil, fmt.Errorf("create savepoint error: %v", err)
+	}
+	return textResult(map[string]interface{}{"tx_id": txID, "savepoint": name})
+}
+
+// Handle batch execution requests inside an already-open transaction. Each
+// statement goes through the same AST-based write guard as mysql_execute; the
+// first failing statement rolls back and ends the whole transaction instead
+// of leaving it half-applied.
+func handleTxExec(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	txID, err := requiredStringArg(request.Arguments, "tx_id")
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := request.Arguments["statements"].([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, errors.New("statements must be a non-empty array of SQL strings")
+	}
+	statements := make([]string, len(raw))
+	for i, s := range raw {
+		stmt, ok := s.(string)
+		if !ok {
+			return nil, fmt.Errorf("statements[%d] must be a string", i)
+		}
+		statements[i] = stmt
+	}
+
+	conn, err := dbManager.TxConnection(txID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*db.ExecuteResult, 0, len(statements))
+	for i, stmtSQL := range statements {
+		stmt, err := sqlsafety.CheckWriteAllowed(stmtSQL)
+		if err != nil {
+			dbManager.RollbackTx(txID)
+			return nil, fmt.Errorf("statement %d rejected, transaction rolled back: %v", i+1, err)
+		}
+		if err := checkTablePermission(conn, stmt); err != nil {
+			dbManager.RollbackTx(txID)
+			return nil, fmt.Errorf("statement %d rejected, transaction rolled back: %v", i+1, err)
+		}
+
+		result, err := dbManager.ExecuteTx(txID, stmtSQL)
+		if err != nil {
+			dbManager.RollbackTx(txID)
+			return nil, fmt.Errorf("statement %d failed, transaction rolled back: %v", i+1, err)
+		}
+		results = append(results, result)
+	}
+
+	return textResult(map[string]interface{}{"tx_id": txID, "results": results})
+}
+
+// Handle shard routing explain requests
+func handleExplainRoute(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	sql, ok := request.Arguments["sql"].(string)
+	if !ok {
+		return nil, errors.New("sql must be a string")
+	}
+
+	plan, err := sqlRouter.Explain(sql)
+	if err != nil {
+		return nil, fmt.Errorf("route explain error: %v", err)
+	}
+
+	jsonData, err := json.Marshal(plan)
+	if err != nil {
+		return nil, fmt.Errorf("JSON serialization failed: %v", err)
+	}
+
+	return &protocol.CallToolResult{
+		Content: []protocol.Content{
+			protocol.TextContent{
+				Type: "text",
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// Handle connection discovery requests
+func handleListConnections(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	names := dbManager.ConnectionNames()
+	connections := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		permission := dbManager.Permission(name)
+		connections = append(connections, map[string]interface{}{
+			"name":         name,
+			"allow_query":  permission.AllowQuery,
+			"allow_insert": permission.AllowInsert,
+			"allow_update": permission.AllowUpdate,
+			"allow_delete": permission.AllowDelete,
+		})
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{"connections": connections})
+	if err != nil {
+		return nil, fmt.Errorf("JSON serialization failed: %v", err)
 	}
 
 	return &protocol.CallToolResult{
@@ -390,3 +1888,16 @@ func handleExecute(request *protocol.CallToolRequest) (*protocol.CallToolResult,
 		},
 	}, nil
 }
+
+// Handle health/diagnostics requests: per-connection pool stats plus the
+// recent slow-query log, the same data the /healthz and /metrics HTTP
+// endpoints expose when metrics_addr is configured
+func handleHealth(request *protocol.CallToolRequest) (*protocol.CallToolResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	return textResult(map[string]interface{}{
+		"connections":  dbManager.Stats(ctx),
+		"slow_queries": dbManager.SlowQueries(),
+	})
+}
@@ -0,0 +1,137 @@
+// Package audit 为每次MCP工具调用写一条结构化JSON审计日志（JSON Lines，
+// 每条记录独占一行），记录调用的工具、目标连接、SQL语句、参数摘要、返回行数、
+// 耗时、是否触发了脱敏以及错误信息，供事后追溯谁在什么时候对哪个库执行了
+// 什么操作。
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBytes 是 Logger 未显式配置最大文件大小时使用的滚动阈值（10 MiB）
+const DefaultMaxBytes = 10 << 20
+
+// Record 是写入审计日志的一条结构化记录
+type Record struct {
+	Time time.Time `json:"time"`
+	Tool string    `json:"tool"`
+	// Connection 是本次调用显式传入的 "connection" 参数；调用未传该参数时
+	// （包括落到默认连接的调用，以及不涉及具体连接的工具如
+	// mysql_list_connections）留空
+	Connection string `json:"connection,omitempty"`
+	// SQL 是本次调用执行的SQL文本，已经过 pkg/redact 按配置规则脱敏
+	SQL string `json:"sql,omitempty"`
+	// ArgsHash 是调用参数的SHA-256摘要，记录摘要而非明文以避免参数中的敏感
+	// 值落盘
+	ArgsHash string `json:"args_hash,omitempty"`
+	// CallerID 标识发起调用的MCP客户端；go-mcp v0.1.14 的 ToolHandlerFunc
+	// 不会把会话/客户端身份传给工具处理函数，因此该字段目前始终为空，等
+	// go-mcp在未来版本中暴露该信息后再填充
+	CallerID   string `json:"caller_id,omitempty"`
+	RowCount   int    `json:"row_count,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	// Redacted 表示本次调用的SQL或结果是否触发了脱敏规则
+	Redacted bool   `json:"redacted,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Logger 把 Record 序列化为JSON并追加写入一个滚动的文件sink
+type Logger struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+// NewLogger 打开（或创建）path用于追加写入，超过maxBytes（<=0时使用
+// DefaultMaxBytes）后滚动为 path+".1"（仅保留一份滚动备份）。path为空时
+// 返回一个nil *Logger，Log/Close在其上调用均是no-op，方便整体关闭审计功能
+// 而不必让每个调用方判空。
+func NewLogger(path string, maxBytes int64) (*Logger, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志文件失败: %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("读取审计日志文件信息失败: %v", err)
+	}
+
+	return &Logger{path: path, maxBytes: maxBytes, file: f, size: info.Size()}, nil
+}
+
+// Log 写入一条审计记录，写入前按 maxBytes 判断是否需要先滚动文件。
+// l为nil（未配置审计日志路径）时是no-op。序列化失败或写入失败时该条记录被
+// 静默丢弃，不会让调用方的工具调用因为审计失败而失败。
+func (l *Logger) Log(r Record) {
+	if l == nil {
+		return
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size > 0 && l.size+int64(len(data)) > l.maxBytes {
+		l.rotate()
+	}
+	n, err := l.file.Write(data)
+	if err == nil {
+		l.size += int64(n)
+	}
+}
+
+// rotate 关闭当前文件，将其重命名为一份滚动备份（覆盖之前的备份），并重新
+// 打开一个空文件；调用方必须已持有 l.mu。
+func (l *Logger) rotate() {
+	l.file.Close()
+	backupPath := l.path + ".1"
+	_ = os.Rename(l.path, backupPath)
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_TRUNC, 0644)
+	if err != nil {
+		// 无法重新打开文件时退回到原文件路径的追加写入，保证后续Log调用
+		// 至少还有地方可写
+		f, _ = os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	}
+	l.file = f
+	l.size = 0
+}
+
+// Close 关闭底层文件。l为nil时是no-op。
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// HashArgs 返回调用参数的SHA-256摘要（十六进制），审计日志记录摘要而非明文，
+// 避免把可能包含敏感值的查询参数整个落盘
+func HashArgs(args map[string]interface{}) string {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // DBConfig 包含单个数据库的配置信息
@@ -15,6 +16,71 @@ type DBConfig struct {
 	User     string `json:"user"`
 	Password string `json:"password"`
 	DBName   string `json:"dbname"`
+	// Permission 为该连接单独指定的权限配置；为空时回退到 Config.Permission
+	Permission *Permission `json:"permission,omitempty"`
+
+	// MaxOpenConns 是该连接池允许的最大打开连接数；<=0 时使用 DefaultMaxOpenConns
+	MaxOpenConns int `json:"max_open_conns,omitempty"`
+	// MaxIdleConns 是该连接池保留的最大空闲连接数；<=0 时使用 DefaultMaxIdleConns
+	MaxIdleConns int `json:"max_idle_conns,omitempty"`
+	// ConnMaxLifetimeSeconds 是单个连接的最长存活时间（秒）；<=0 时使用
+	// DefaultConnMaxLifetime
+	ConnMaxLifetimeSeconds int `json:"conn_max_lifetime_seconds,omitempty"`
+	// ConnMaxIdleTimeSeconds 是单个连接允许的最长空闲时间（秒）；<=0 时使用
+	// DefaultConnMaxIdleTime
+	ConnMaxIdleTimeSeconds int `json:"conn_max_idle_time_seconds,omitempty"`
+	// PingTimeoutSeconds 是启动时探测该连接的超时时间（秒）；<=0 时使用
+	// DefaultPingTimeout
+	PingTimeoutSeconds int `json:"ping_timeout_seconds,omitempty"`
+}
+
+// 连接池参数未在 DBConfig 中显式配置时使用的默认值
+const (
+	DefaultMaxOpenConns    = 10
+	DefaultMaxIdleConns    = 5
+	DefaultConnMaxLifetime = 3 * time.Minute
+	DefaultConnMaxIdleTime = 0 // 0表示不限制，与 database/sql 的零值语义一致
+	DefaultPingTimeout     = 5 * time.Second
+)
+
+// MaxOpenConnsOrDefault 返回配置的最大打开连接数，未配置（<=0）时返回默认值
+func (c DBConfig) MaxOpenConnsOrDefault() int {
+	if c.MaxOpenConns > 0 {
+		return c.MaxOpenConns
+	}
+	return DefaultMaxOpenConns
+}
+
+// MaxIdleConnsOrDefault 返回配置的最大空闲连接数，未配置（<=0）时返回默认值
+func (c DBConfig) MaxIdleConnsOrDefault() int {
+	if c.MaxIdleConns > 0 {
+		return c.MaxIdleConns
+	}
+	return DefaultMaxIdleConns
+}
+
+// ConnMaxLifetimeOrDefault 返回配置的连接最长存活时间，未配置（<=0）时返回默认值
+func (c DBConfig) ConnMaxLifetimeOrDefault() time.Duration {
+	if c.ConnMaxLifetimeSeconds > 0 {
+		return time.Duration(c.ConnMaxLifetimeSeconds) * time.Second
+	}
+	return DefaultConnMaxLifetime
+}
+
+// ConnMaxIdleTimeOrDefault 返回配置的连接最长空闲时间，未配置（<=0）时返回默认值
+func (c DBConfig) ConnMaxIdleTimeOrDefault() time.Duration {
+	if c.ConnMaxIdleTimeSeconds > 0 {
+		return time.Duration(c.ConnMaxIdleTimeSeconds) * time.Second
+	}
+	return DefaultConnMaxIdleTime
+}
+
+// PingTimeoutOrDefault 返回配置的启动探测超时时间，未配置（<=0）时返回默认值
+func (c DBConfig) PingTimeoutOrDefault() time.Duration {
+	if c.PingTimeoutSeconds > 0 {
+		return time.Duration(c.PingTimeoutSeconds) * time.Second
+	}
+	return DefaultPingTimeout
 }
 
 // Permission 权限配置
@@ -23,12 +89,78 @@ type Permission struct {
 	AllowInsert bool `json:"allow_insert"`
 	AllowUpdate bool `json:"allow_update"`
 	AllowDelete bool `json:"allow_delete"`
+	// AllowedTables 非空时为白名单：只有出现在其中的表才允许被访问
+	AllowedTables []string `json:"allowed_tables,omitempty"`
+	// DeniedTables 中的表始终被拒绝，优先级高于 AllowedTables
+	DeniedTables []string `json:"denied_tables,omitempty"`
+	// AllowRawPII 为true时放行会暴露 Redaction 配置中敏感列的 "SELECT *"
+	// 查询；默认拒绝，要求调用方显式点名要取的列
+	AllowRawPII bool `json:"allow_raw_pii,omitempty"`
+}
+
+// TableAllowed 判断给定表名在该权限配置下是否允许被访问：DeniedTables 中的表
+// 始终拒绝；AllowedTables 非空时只放行其中列出的表；两者都未配置时不做限制
+func (p Permission) TableAllowed(table string) bool {
+	for _, denied := range p.DeniedTables {
+		if strings.EqualFold(denied, table) {
+			return false
+		}
+	}
+	if len(p.AllowedTables) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedTables {
+		if strings.EqualFold(allowed, table) {
+			return true
+		}
+	}
+	return false
 }
 
 // Config 应用配置结构
 type Config struct {
 	Databases  map[string]DBConfig `json:"databases"`
 	Permission Permission          `json:"permission"`
+	// ShardRules 声明需要跨多个连接分片的逻辑表；未配置分片规则的表按
+	// Databases/connection 参数正常单点路由
+	ShardRules []ShardRule `json:"shard_rules,omitempty"`
+	// MetricsAddr 非空时在该地址上启动 /healthz 与 /metrics HTTP端点；
+	// 为空时不启动该监听器
+	MetricsAddr string `json:"metrics_addr,omitempty"`
+	// Redaction 声明对查询结果与SQL文本做脱敏的规则，由 pkg/redact 使用
+	Redaction Redaction `json:"redaction,omitempty"`
+}
+
+// Redaction 声明敏感数据的脱敏规则
+type Redaction struct {
+	// Columns 是按列名匹配（忽略大小写）的敏感列，例如 "password"、"id_card"、"phone"
+	Columns []string `json:"columns,omitempty"`
+	// Patterns 是应用于字符串值与SQL文本的正则表达式；匹配到的片段会被替换为掩码
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// ShardFunc 分片函数类型
+type ShardFunc string
+
+const (
+	ShardFuncHash  ShardFunc = "hash"
+	ShardFuncMod   ShardFunc = "mod"
+	ShardFuncRange ShardFunc = "range"
+)
+
+// ShardRangeBound 描述 range 分片函数的一个区间：分片键小于等于 Max 时落在 Nodes 上
+type ShardRangeBound struct {
+	Max   int64    `json:"max"`
+	Nodes []string `json:"nodes"`
+}
+
+// ShardRule 描述某张逻辑表的分片规则，Nodes/Ranges 中的连接名须在 Databases 中声明
+type ShardRule struct {
+	Table     string            `json:"table"`
+	ShardKey  string            `json:"shard_key"`
+	ShardFunc ShardFunc         `json:"shard_func"`
+	Nodes     []string          `json:"nodes"`
+	Ranges    []ShardRangeBound `json:"ranges,omitempty"`
 }
 
 // DefaultConfig 返回默认配置
@@ -58,7 +190,7 @@ func LoadConfig(path string) (*Config, error) {
 	if envConfig := LoadConfigFromEnv(); envConfig != nil {
 		return envConfig, nil
 	}
-	
+
 	// 尝试从MCP服务器参数的env字段加载配置
 	if mcpEnvConfig := LoadConfigFromMCPEnv(); mcpEnvConfig != nil {
 		return mcpEnvConfig, nil
@@ -98,7 +230,7 @@ func LoadConfigFromEnv() *Config {
 
 	// 读取环境变量（支持多种可能的环境变量名称）
 	host := os.Getenv("MYSQL_HOST")
-	
+
 	// 尝试解析端口
 	var port int = 3306 // 默认端口
 	portStr := os.Getenv("MYSQL_PORT")
@@ -107,33 +239,33 @@ func LoadConfigFromEnv() *Config {
 			port = p
 		}
 	}
-	
+
 	// 获取用户名
 	user := os.Getenv("MYSQL_USER")
-	
+
 	// 尝试获取密码（支持多种可能的环境变量名）
 	pass := os.Getenv("MYSQL_PASS")
 	if pass == "" {
 		pass = os.Getenv("MYSQL_PASSWORD") // 尝试备选名称
 	}
-	
+
 	// 尝试获取数据库名（支持多种可能的环境变量名）
 	dbName := os.Getenv("MYSQL_DB")
 	if dbName == "" {
 		dbName = os.Getenv("DEFAULT_DATABASE") // 尝试备选名称
 	}
-	
+
 	// 获取权限配置（支持多种可能的环境变量名）
 	allowInsertStr := os.Getenv("ALLOW_INSERT_OPERATION")
 	if allowInsertStr == "" {
 		allowInsertStr = os.Getenv("ALLOW_INSERT")
 	}
-	
+
 	allowUpdateStr := os.Getenv("ALLOW_UPDATE_OPERATION")
 	if allowUpdateStr == "" {
 		allowUpdateStr = os.Getenv("ALLOW_UPDATE")
 	}
-	
+
 	allowDeleteStr := os.Getenv("ALLOW_DELETE_OPERATION")
 	if allowDeleteStr == "" {
 		allowDeleteStr = os.Getenv("ALLOW_DELETE")
@@ -192,7 +324,7 @@ func loadConfigFromJsonEnv() *Config {
 	// 检查是否有数据库配置环境变量
 	databasesEnv := os.Getenv("databases")
 	permissionEnv := os.Getenv("permission")
-	
+
 	// 嵌套环境变量解析 - 处理MCP启动配置中的env结构
 	// 在这种情况下，用户使用的是类似于以下格式：
 	// env: {
@@ -203,10 +335,10 @@ func loadConfigFromJsonEnv() *Config {
 		// 检查是否有嵌套在env下的配置
 		dbJsonStr := os.Getenv("env.databases")
 		permJsonStr := os.Getenv("env.permission")
-		
+
 		if dbJsonStr != "" || permJsonStr != "" {
 			config := DefaultConfig()
-			
+
 			// 处理数据库配置
 			if dbJsonStr != "" {
 				var databases map[string]DBConfig
@@ -214,7 +346,7 @@ func loadConfigFromJsonEnv() *Config {
 					config.Databases = databases
 				}
 			}
-			
+
 			// 处理权限配置
 			if permJsonStr != "" {
 				var permission Permission
@@ -222,10 +354,10 @@ func loadConfigFromJsonEnv() *Config {
 					config.Permission = permission
 				}
 			}
-			
+
 			return config
 		}
-		
+
 		// 尝试解析完整的env字段
 		envJsonStr := os.Getenv("env")
 		if envJsonStr != "" {
@@ -233,21 +365,21 @@ func loadConfigFromJsonEnv() *Config {
 				Databases  map[string]DBConfig `json:"databases"`
 				Permission Permission          `json:"permission"`
 			}
-			
+
 			if err := json.Unmarshal([]byte(envJsonStr), &configData); err == nil {
 				config := DefaultConfig()
-				
+
 				if len(configData.Databases) > 0 {
 					config.Databases = configData.Databases
 				}
-				
+
 				// 只覆盖非空的权限配置
 				config.Permission = configData.Permission
-				
+
 				return config
 			}
 		}
-		
+
 		return nil
 	}
 
@@ -305,7 +437,7 @@ func LoadConfigFromMCPEnv() *Config {
 			Password: os.Getenv("env.databases.default.password"),
 			DBName:   os.Getenv("env.databases.default.dbname"),
 		}
-		
+
 		// 尝试解析端口
 		portStr := os.Getenv("env.databases.default.port")
 		if portStr != "" {
@@ -313,18 +445,18 @@ func LoadConfigFromMCPEnv() *Config {
 				dbConfig.Port = port
 			}
 		}
-		
+
 		// 更新数据库配置
 		config.Databases["default"] = dbConfig
-		
+
 		// 处理权限配置
 		config.Permission.AllowQuery = parseBoolEnv(os.Getenv("env.permission.allow_query"), true)
 		config.Permission.AllowInsert = parseBoolEnv(os.Getenv("env.permission.allow_insert"), false)
 		config.Permission.AllowUpdate = parseBoolEnv(os.Getenv("env.permission.allow_update"), false)
 		config.Permission.AllowDelete = parseBoolEnv(os.Getenv("env.permission.allow_delete"), false)
-		
+
 		return config
 	}
-	
+
 	return nil
 }
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// EncodingRow 是默认的按行布局：Rows 的每个元素是一行的列值
+const EncodingRow = "row"
+
+// EncodingColumnar 将 Rows 转置为按列布局：Rows 的每个元素是一列在所有行上的
+// 取值。同一列重复出现的值相邻排列，通常比按行布局更利于下游压缩/摘要
+const EncodingColumnar = "columnar"
+
+// binaryTypes 是 DatabaseTypeName() 返回值中代表二进制（而非按字符集编码的文本）
+// 列的类型名，这些列的原始字节用base64编码，而不是当作UTF-8文本直接转换
+var binaryTypes = map[string]bool{
+	"BLOB":       true,
+	"TINYBLOB":   true,
+	"MEDIUMBLOB": true,
+	"LONGBLOB":   true,
+	"BINARY":     true,
+	"VARBINARY":  true,
+}
+
+// decimalTypes 是 DatabaseTypeName() 返回值中代表定点数的类型名
+var decimalTypes = map[string]bool{
+	"DECIMAL":    true,
+	"NEWDECIMAL": true,
+}
+
+// convertValue 依据列的 DatabaseTypeName() 把驱动返回的原始值转换成更忠实的
+// JSON表示：时间类型转ISO-8601字符串，DECIMAL转数字JSON（decimalAsString时退化为
+// 字符串以保留精度），BLOB/BINARY/VARBINARY转base64，JSON列解析成对象/数组，
+// SET列拆分成字符串数组，BIT列转成无符号整数。其余类型按驱动原有行为处理。
+func convertValue(v interface{}, typeName string, decimalAsString bool) interface{} {
+	switch raw := v.(type) {
+	case time.Time:
+		return raw.Format(time.RFC3339Nano)
+	case []byte:
+		switch {
+		case binaryTypes[typeName]:
+			return base64.StdEncoding.EncodeToString(raw)
+		case typeName == "JSON":
+			var parsed interface{}
+			if err := json.Unmarshal(raw, &parsed); err == nil {
+				return parsed
+			}
+			return string(raw)
+		case typeName == "SET":
+			if len(raw) == 0 {
+				return []string{}
+			}
+			return strings.Split(string(raw), ",")
+		case typeName == "BIT":
+			return bitsToUint(raw)
+		case decimalTypes[typeName]:
+			if decimalAsString {
+				return string(raw)
+			}
+			return json.Number(raw)
+		default:
+			return string(raw)
+		}
+	default:
+		return v
+	}
+}
+
+// bitsToUint 把 BIT 列的大端字节表示转换成无符号整数
+func bitsToUint(b []byte) uint64 {
+	var n uint64
+	for _, by := range b {
+		n = n<<8 | uint64(by)
+	}
+	return n
+}
+
+// toColumnar 把按行排列的 rows 转置为按列排列
+func toColumnar(rows [][]interface{}, columnCount int) [][]interface{} {
+	columnar := make([][]interface{}, columnCount)
+	for c := 0; c < columnCount; c++ {
+		columnar[c] = make([]interface{}, len(rows))
+		for r, row := range rows {
+			columnar[c][r] = row[c]
+		}
+	}
+	return columnar
+}
@@ -1,12 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/config"
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/sqlsafety"
 	_ "github.com/go-sql-driver/mysql"
 )
 
@@ -14,13 +16,69 @@ import (
 type DBManager struct {
 	connections map[string]*sql.DB
 	config      *config.Config
+
+	txMu sync.Mutex
+	txs  map[string]*txHandle
+
+	slowMu  sync.Mutex
+	slowLog []SlowQuery
+
+	stopReaper chan struct{}
+}
+
+// queryExecer 是 *sql.DB 与 *sql.Tx 的公共子集，使得查询/执行逻辑既能直接
+// 作用于某个连接，也能在 BeginTx 开启的事务内复用同一套实现
+type queryExecer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
 // QueryResult 查询结果结构
 type QueryResult struct {
-	Columns []string        `json:"columns"`
-	Rows    [][]interface{} `json:"rows"`
-	Error   string          `json:"error,omitempty"`
+	Columns    []string        `json:"columns"`
+	Types      []string        `json:"types,omitempty"`
+	Rows       [][]interface{} `json:"rows"`
+	Columnar   bool            `json:"columnar,omitempty"`
+	Truncated  bool            `json:"truncated,omitempty"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	// Redacted is set by the caller (not by this package, which has no notion
+	// of redaction rules) when pkg/redact masked one or more values in Rows
+	Redacted bool   `json:"redacted,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// QueryOptions 约束一次查询实际取回多少数据，避免大表把MCP进程撑爆内存
+// 或产生LLM无法消费的超大JSON
+type QueryOptions struct {
+	// MaxRows 达到该行数后停止继续扫描结果集；0表示使用DefaultMaxRows
+	MaxRows int
+	// MaxBytes 是行数据的粗略累计大小上限（字节），达到后提前停止扫描；
+	// 0表示使用DefaultMaxBytes
+	MaxBytes int
+	// DecimalAsString 为true时DECIMAL列以字符串形式返回（保留精度），否则
+	// 以 json.Number 形式返回不带引号的数字JSON
+	DecimalAsString bool
+	// Encoding 是 EncodingRow（默认）或 EncodingColumnar
+	Encoding string
+}
+
+// DefaultMaxRows 是未显式传入 QueryOptions.MaxRows 时使用的默认行数上限
+const DefaultMaxRows = 500
+
+// DefaultMaxBytes 是未显式传入 QueryOptions.MaxBytes 时使用的默认字节数上限（1 MiB）
+const DefaultMaxBytes = 1 << 20
+
+func (o QueryOptions) withDefaults() QueryOptions {
+	if o.MaxRows <= 0 {
+		o.MaxRows = DefaultMaxRows
+	}
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultMaxBytes
+	}
+	if o.Encoding == "" {
+		o.Encoding = EncodingRow
+	}
+	return o
 }
 
 // ExecuteResult 执行结果结构
@@ -30,11 +88,25 @@ type ExecuteResult struct {
 	Error        string `json:"error,omitempty"`
 }
 
+// BuildArgs 将位置参数与命名参数合并为 database/sql 可接受的参数列表。
+// 位置参数对应 SQL 中的 ? 占位符；命名参数会通过 sql.Named 转换，
+// 以配合驱动对 :name 风格占位符的支持。
+func BuildArgs(positional []interface{}, named map[string]interface{}) []interface{} {
+	args := make([]interface{}, 0, len(positional)+len(named))
+	args = append(args, positional...)
+	for name, v := range named {
+		args = append(args, sql.Named(name, v))
+	}
+	return args
+}
+
 // NewDBManager 创建数据库管理器实例
 func NewDBManager(cfg *config.Config) (*DBManager, error) {
 	manager := &DBManager{
 		connections: make(map[string]*sql.DB),
 		config:      cfg,
+		txs:         make(map[string]*txHandle),
+		stopReaper:  make(chan struct{}),
 	}
 
 	// 连接所有配置的数据库
@@ -53,24 +125,31 @@ func NewDBManager(cfg *config.Config) (*DBManager, error) {
 			return nil, fmt.Errorf("连接数据库 %s 失败: %v", name, err)
 		}
 
-		// 设置连接池参数
-		db.SetMaxOpenConns(10)
-		db.SetMaxIdleConns(5)
-		db.SetConnMaxLifetime(time.Minute * 3)
+		// 设置连接池参数，均可通过该连接的 DBConfig 单独配置，未配置时使用默认值
+		db.SetMaxOpenConns(dbConfig.MaxOpenConnsOrDefault())
+		db.SetMaxIdleConns(dbConfig.MaxIdleConnsOrDefault())
+		db.SetConnMaxLifetime(dbConfig.ConnMaxLifetimeOrDefault())
+		db.SetConnMaxIdleTime(dbConfig.ConnMaxIdleTimeOrDefault())
 
-		// 测试连接
-		if err := db.Ping(); err != nil {
+		// 测试连接，超时时间同样可配置，避免启动时因网络问题无限阻塞
+		pingCtx, cancel := context.WithTimeout(context.Background(), dbConfig.PingTimeoutOrDefault())
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err != nil {
 			return nil, fmt.Errorf("ping 数据库 %s 失败: %v", name, err)
 		}
 
 		manager.connections[name] = db
 	}
 
+	go manager.reapIdleTx()
+
 	return manager, nil
 }
 
-// Close 关闭所有数据库连接
+// Close 关闭所有数据库连接，并停止空闲事务回收goroutine
 func (m *DBManager) Close() {
+	close(m.stopReaper)
 	for _, db := range m.connections {
 		db.Close()
 	}
@@ -85,10 +164,50 @@ func (m *DBManager) GetDB(name string) (*sql.DB, error) {
 	return db, nil
 }
 
-// Query 执行查询操作
+// ConnectionNames 返回当前已配置的数据库连接名称列表
+func (m *DBManager) ConnectionNames() []string {
+	names := make([]string, 0, len(m.connections))
+	for name := range m.connections {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Permission 返回指定连接生效的权限配置：如果该连接单独配置了权限则使用连接级配置，
+// 否则回退到全局权限配置
+func (m *DBManager) Permission(name string) config.Permission {
+	if dbConfig, ok := m.config.Databases[name]; ok && dbConfig.Permission != nil {
+		return *dbConfig.Permission
+	}
+	return m.config.Permission
+}
+
+// DatabaseName 返回指定连接在配置中声明的 dbname（即该连接DSN所use的数据库），
+// 供需要据此做schema自省的调用方使用（例如判断 SELECT * 是否会暴露脱敏列）
+func (m *DBManager) DatabaseName(name string) (string, error) {
+	dbConfig, ok := m.config.Databases[name]
+	if !ok {
+		return "", fmt.Errorf("数据库 %s 未配置", name)
+	}
+	return dbConfig.DBName, nil
+}
+
+// Query 执行查询操作，不附带超时控制
 func (m *DBManager) Query(dbName, query string, args ...interface{}) (*QueryResult, error) {
-	if !m.config.Permission.AllowQuery {
-		return nil, fmt.Errorf("查询操作未被允许")
+	return m.QueryContext(context.Background(), dbName, query, args...)
+}
+
+// QueryContext 执行查询操作，ctx 超时或取消时底层驱动会中断查询，结果按
+// DefaultMaxRows/DefaultMaxBytes截断。需要自定义上限时用 QueryContextWithOptions。
+func (m *DBManager) QueryContext(ctx context.Context, dbName, query string, args ...interface{}) (*QueryResult, error) {
+	return m.QueryContextWithOptions(ctx, dbName, query, QueryOptions{}, args...)
+}
+
+// QueryContextWithOptions 执行查询操作，并在结果集扫描过程中按 opts 的行数/
+// 字节数上限提前停止，而不是先把整个结果集读入内存再截断
+func (m *DBManager) QueryContextWithOptions(ctx context.Context, dbName, query string, opts QueryOptions, args ...interface{}) (*QueryResult, error) {
+	if !m.Permission(dbName).AllowQuery {
+		return nil, fmt.Errorf("数据库 %s 的查询操作未被允许", dbName)
 	}
 
 	db, err := m.GetDB(dbName)
@@ -96,8 +215,20 @@ func (m *DBManager) Query(dbName, query string, args ...interface{}) (*QueryResu
 		return &QueryResult{Error: err.Error()}, err
 	}
 
+	start := time.Now()
+	result, err := runQuery(ctx, db, opts, query, args...)
+	m.recordSlowQuery(dbName, query, time.Since(start))
+	return result, err
+}
+
+// runQuery 在给定的连接或事务上执行查询并收集结果，供 QueryContext 和
+// QueryTx 共用。达到 opts.MaxRows 行或累计行数据超过 opts.MaxBytes 时提前
+// 停止扫描并将 Truncated 置为 true，避免大表把结果集整个读入内存。
+func runQuery(ctx context.Context, q queryExecer, opts QueryOptions, query string, args ...interface{}) (*QueryResult, error) {
+	opts = opts.withDefaults()
+
 	// 执行查询
-	rows, err := db.Query(query, args...)
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return &QueryResult{Error: err.Error()}, err
 	}
@@ -109,14 +240,30 @@ func (m *DBManager) Query(dbName, query string, args ...interface{}) (*QueryResu
 		return &QueryResult{Error: err.Error()}, err
 	}
 
+	// 获取每列的数据库类型名，用于convertValue按类型做忠实转换
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return &QueryResult{Error: err.Error()}, err
+	}
+	typeNames := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		typeNames[i] = ct.DatabaseTypeName()
+	}
+
 	// 准备结果
 	result := &QueryResult{
 		Columns: columns,
+		Types:   typeNames,
 		Rows:    make([][]interface{}, 0),
 	}
 
-	// 遍历结果集
+	byteCount := 0
 	for rows.Next() {
+		if len(result.Rows) >= opts.MaxRows {
+			result.Truncated = true
+			break
+		}
+
 		// 创建一个列值的slice
 		values := make([]interface{}, len(columns))
 		// 创建一个接收指针的slice
@@ -132,24 +279,24 @@ func (m *DBManager) Query(dbName, query string, args ...interface{}) (*QueryResu
 			return &QueryResult{Error: err.Error()}, err
 		}
 
-		// 处理结果，转换为合适的类型
+		// 处理结果，按每列的数据库类型转换为忠实的JSON表示
 		row := make([]interface{}, len(columns))
 		for i, v := range values {
 			if v == nil {
 				row[i] = nil
 				continue
 			}
+			row[i] = convertValue(v, typeNames[i], opts.DecimalAsString)
+		}
 
-			// 根据数据类型转换
-			switch v.(type) {
-			case []byte:
-				row[i] = string(v.([]byte))
-			default:
-				row[i] = v
-			}
+		rowBytes := estimateRowBytes(row)
+		if len(result.Rows) > 0 && byteCount+rowBytes > opts.MaxBytes {
+			result.Truncated = true
+			break
 		}
 
 		result.Rows = append(result.Rows, row)
+		byteCount += rowBytes
 	}
 
 	// 检查遍历过程中是否有错误
@@ -157,26 +304,28 @@ func (m *DBManager) Query(dbName, query string, args ...interface{}) (*QueryResu
 		return &QueryResult{Error: err.Error()}, err
 	}
 
+	if opts.Encoding == EncodingColumnar {
+		result.Rows = toColumnar(result.Rows, len(columns))
+		result.Columnar = true
+	}
+
 	return result, nil
 }
 
+// estimateRowBytes 粗略估算一行数据序列化后的字节数，用于 QueryOptions.MaxBytes
+// 判断，不追求精确匹配最终JSON大小
+func estimateRowBytes(row []interface{}) int {
+	size := 0
+	for _, v := range row {
+		size += len(fmt.Sprint(v))
+	}
+	return size
+}
+
 // Execute 执行插入/更新/删除操作
 func (m *DBManager) Execute(dbName, query string, args ...interface{}) (*ExecuteResult, error) {
-	// 根据SQL语句类型检查权限
-	lowerQuery := strings.TrimSpace(strings.ToLower(query))
-
-	if strings.HasPrefix(lowerQuery, "insert") {
-		if !m.config.Permission.AllowInsert {
-			return nil, fmt.Errorf("插入操作未被允许")
-		}
-	} else if strings.HasPrefix(lowerQuery, "update") {
-		if !m.config.Permission.AllowUpdate {
-			return nil, fmt.Errorf("更新操作未被允许")
-		}
-	} else if strings.HasPrefix(lowerQuery, "delete") {
-		if !m.config.Permission.AllowDelete {
-			return nil, fmt.Errorf("删除操作未被允许")
-		}
+	if err := m.checkWritePermission(dbName, query); err != nil {
+		return nil, err
 	}
 
 	db, err := m.GetDB(dbName)
@@ -184,8 +333,44 @@ func (m *DBManager) Execute(dbName, query string, args ...interface{}) (*Execute
 		return &ExecuteResult{Error: err.Error()}, err
 	}
 
+	start := time.Now()
+	result, err := runExecute(context.Background(), db, query, args...)
+	m.recordSlowQuery(dbName, query, time.Since(start))
+	return result, err
+}
+
+// checkWritePermission 按连接配置的 Permission.AllowInsert/AllowUpdate/
+// AllowDelete 校验一条写语句是否被允许，依据解析得到的AST节点类型分类，而不
+// 是字符串前缀——一段打头的注释（如 "/*x*/ INSERT ..."）就能让前缀匹配落空，
+// 从而让本应被拒绝的写操作绕过权限检查。Execute 和 ExecuteTx 共用该校验，
+// 确保进入事务内也不能绕开连接的写权限。
+func (m *DBManager) checkWritePermission(dbName, query string) error {
+	_, kind, err := sqlsafety.ClassifyWrite(query)
+	if err != nil {
+		return err
+	}
+	permission := m.Permission(dbName)
+	switch kind {
+	case sqlsafety.WriteInsert:
+		if !permission.AllowInsert {
+			return fmt.Errorf("数据库 %s 的插入操作未被允许", dbName)
+		}
+	case sqlsafety.WriteUpdate:
+		if !permission.AllowUpdate {
+			return fmt.Errorf("数据库 %s 的更新操作未被允许", dbName)
+		}
+	case sqlsafety.WriteDelete:
+		if !permission.AllowDelete {
+			return fmt.Errorf("数据库 %s 的删除操作未被允许", dbName)
+		}
+	}
+	return nil
+}
+
+// runExecute 在给定的连接或事务上执行写操作，供 Execute 和 ExecuteTx 共用
+func runExecute(ctx context.Context, q queryExecer, query string, args ...interface{}) (*ExecuteResult, error) {
 	// 执行操作
-	result, err := db.Exec(query, args...)
+	result, err := q.ExecContext(ctx, query, args...)
 	if err != nil {
 		return &ExecuteResult{Error: err.Error()}, err
 	}
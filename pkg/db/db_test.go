@@ -0,0 +1,135 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/config"
+)
+
+func TestBuildArgs(t *testing.T) {
+	args := BuildArgs([]interface{}{1, "a"}, map[string]interface{}{"name": "bob"})
+	if len(args) != 3 {
+		t.Fatalf("BuildArgs returned %d args, want 3", len(args))
+	}
+	if args[0] != 1 || args[1] != "a" {
+		t.Fatalf("positional args not preserved in order: %v", args)
+	}
+	named, ok := args[2].(sql.NamedArg)
+	if !ok {
+		t.Fatalf("expected named arg to be wrapped as sql.NamedArg, got %T", args[2])
+	}
+	if named.Name != "name" || named.Value != "bob" {
+		t.Fatalf("unexpected named arg: %+v", named)
+	}
+}
+
+func TestBuildArgsEmpty(t *testing.T) {
+	if args := BuildArgs(nil, nil); len(args) != 0 {
+		t.Fatalf("BuildArgs(nil, nil) = %v, want empty", args)
+	}
+}
+
+func newTestManager(perm config.Permission) *DBManager {
+	return &DBManager{
+		config: &config.Config{
+			Databases: map[string]config.DBConfig{
+				"default": {Permission: &perm},
+			},
+			Permission: config.Permission{AllowQuery: true},
+		},
+	}
+}
+
+func TestCheckWritePermission(t *testing.T) {
+	cases := []struct {
+		name    string
+		perm    config.Permission
+		query   string
+		wantErr bool
+	}{
+		{"insert allowed", config.Permission{AllowInsert: true}, "INSERT INTO t (id) VALUES (1)", false},
+		{"insert denied", config.Permission{}, "INSERT INTO t (id) VALUES (1)", true},
+		{"insert denied behind comment", config.Permission{}, "/*sneaky*/ INSERT INTO t (id) VALUES (1)", true},
+		{"update allowed", config.Permission{AllowUpdate: true}, "UPDATE t SET x = 1", false},
+		{"update denied", config.Permission{}, "UPDATE t SET x = 1", true},
+		{"delete allowed", config.Permission{AllowDelete: true}, "DELETE FROM t", false},
+		{"delete denied", config.Permission{}, "DELETE FROM t", true},
+		{"select unaffected", config.Permission{}, "SELECT 1", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newTestManager(tc.perm)
+			err := m.checkWritePermission("default", tc.query)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("checkWritePermission(%q) error = %v, wantErr %v", tc.query, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestConvertValueTime(t *testing.T) {
+	// time.Time values are formatted as RFC3339Nano regardless of column type.
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	v := convertValue(tm, "DATETIME", false)
+	if v != "2024-01-02T03:04:05Z" {
+		t.Fatalf("convertValue(time) = %v", v)
+	}
+}
+
+func TestConvertValueBinary(t *testing.T) {
+	v := convertValue([]byte("hi"), "BLOB", false)
+	if v != "aGk=" {
+		t.Fatalf("convertValue(BLOB) = %v, want base64 \"aGk=\"", v)
+	}
+}
+
+func TestConvertValueJSON(t *testing.T) {
+	v := convertValue([]byte(`{"a":1}`), "JSON", false)
+	m, ok := v.(map[string]interface{})
+	if !ok || m["a"] != float64(1) {
+		t.Fatalf("convertValue(JSON) = %#v, want parsed object", v)
+	}
+}
+
+func TestConvertValueSet(t *testing.T) {
+	v := convertValue([]byte("a,b,c"), "SET", false)
+	set, ok := v.([]string)
+	if !ok || len(set) != 3 || set[0] != "a" {
+		t.Fatalf("convertValue(SET) = %#v", v)
+	}
+}
+
+func TestConvertValueDecimal(t *testing.T) {
+	if v := convertValue([]byte("1.50"), "DECIMAL", true); v != "1.50" {
+		t.Fatalf("convertValue(DECIMAL, decimalAsString=true) = %v", v)
+	}
+	if v := convertValue([]byte("1.50"), "DECIMAL", false); v.(interface{ String() string }).String() != "1.50" {
+		t.Fatalf("convertValue(DECIMAL, decimalAsString=false) = %v, want json.Number", v)
+	}
+}
+
+func TestConvertValuePassthrough(t *testing.T) {
+	if v := convertValue(int64(42), "BIGINT", false); v != int64(42) {
+		t.Fatalf("convertValue(int64) = %v, want passthrough", v)
+	}
+}
+
+func TestToColumnar(t *testing.T) {
+	rows := [][]interface{}{
+		{1, "a"},
+		{2, "b"},
+		{3, "c"},
+	}
+	columnar := toColumnar(rows, 2)
+	if len(columnar) != 2 {
+		t.Fatalf("toColumnar returned %d columns, want 2", len(columnar))
+	}
+	if columnar[0][0] != 1 || columnar[0][1] != 2 || columnar[0][2] != 3 {
+		t.Fatalf("toColumnar column 0 = %v", columnar[0])
+	}
+	if columnar[1][0] != "a" || columnar[1][1] != "b" || columnar[1][2] != "c" {
+		t.Fatalf("toColumnar column 1 = %v", columnar[1])
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DefaultSlowQueryThreshold 是 SlowQueryThreshold 未被覆盖时使用的默认值
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// slowQueryLogSize 是滚动慢查询日志保留的最大条数，超出时丢弃最旧的记录
+const slowQueryLogSize = 100
+
+// SlowQueryThreshold 是判定一次查询/执行为慢查询并计入滚动日志的耗时阈值，
+// 可在进程启动时被覆盖（参见 main 包的 MCP_SLOW_QUERY_THRESHOLD_MS）
+var SlowQueryThreshold = DefaultSlowQueryThreshold
+
+// SlowQuery 记录一次耗时超过 SlowQueryThreshold 的查询/执行
+type SlowQuery struct {
+	Connection string    `json:"connection"`
+	Query      string    `json:"query"`
+	DurationMs int64     `json:"duration_ms"`
+	Time       time.Time `json:"time"`
+}
+
+// recordSlowQuery 在耗时超过 SlowQueryThreshold 时把该次调用计入滚动日志，
+// 超过 slowQueryLogSize 条时丢弃最旧的记录
+func (m *DBManager) recordSlowQuery(connection, query string, dur time.Duration) {
+	if dur < SlowQueryThreshold {
+		return
+	}
+	m.slowMu.Lock()
+	defer m.slowMu.Unlock()
+	m.slowLog = append(m.slowLog, SlowQuery{
+		Connection: connection,
+		Query:      query,
+		DurationMs: dur.Milliseconds(),
+		Time:       time.Now(),
+	})
+	if len(m.slowLog) > slowQueryLogSize {
+		m.slowLog = m.slowLog[len(m.slowLog)-slowQueryLogSize:]
+	}
+}
+
+// SlowQueries 返回当前滚动慢查询日志的快照
+func (m *DBManager) SlowQueries() []SlowQuery {
+	m.slowMu.Lock()
+	defer m.slowMu.Unlock()
+	out := make([]SlowQuery, len(m.slowLog))
+	copy(out, m.slowLog)
+	return out
+}
+
+// ConnectionStats 是单个连接的连接池统计快照及其当前可达性
+type ConnectionStats struct {
+	sql.DBStats
+	PingError string `json:"ping_error,omitempty"`
+}
+
+// Stats 返回每个已配置连接当前的连接池统计信息，并用一次带超时的Ping探测其
+// 可达性，供 mysql_health 工具及 /metrics 端点汇总展示
+func (m *DBManager) Stats(ctx context.Context) map[string]ConnectionStats {
+	stats := make(map[string]ConnectionStats, len(m.connections))
+	for name, sqlDB := range m.connections {
+		s := ConnectionStats{DBStats: sqlDB.Stats()}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			s.PingError = err.Error()
+		}
+		stats[name] = s
+	}
+	return stats
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TxIdleTimeout 是事务的空闲超时时间：超过该时长未被 QueryTx/ExecuteTx 使用的
+// 事务会被后台goroutine自动回滚并清理，避免LLM忘记提交/回滚导致连接被长期占用
+const TxIdleTimeout = 30 * time.Second
+
+// reaperInterval 是空闲事务回收goroutine的轮询周期
+const reaperInterval = 5 * time.Second
+
+// txHandle 持有一个活跃事务及其所属连接，用于空闲超时检测
+type txHandle struct {
+	tx         *sql.Tx
+	connection string
+	lastUsed   time.Time
+}
+
+// BeginTx 在指定连接上开启一个事务，返回供 QueryTx/ExecuteTx/CommitTx/
+// RollbackTx 使用的 tx_id；isolation 为零值（sql.LevelDefault）时使用驱动的
+// 默认隔离级别。
+func (m *DBManager) BeginTx(connection string, isolation sql.IsolationLevel) (string, error) {
+	sqlDB, err := m.GetDB(connection)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := sqlDB.BeginTx(context.Background(), &sql.TxOptions{Isolation: isolation})
+	if err != nil {
+		return "", fmt.Errorf("开启事务失败: %v", err)
+	}
+
+	txID := uuid.NewString()
+	m.txMu.Lock()
+	m.txs[txID] = &txHandle{tx: tx, connection: connection, lastUsed: time.Now()}
+	m.txMu.Unlock()
+
+	log.Printf("[TX BEGIN] tx_id=%s connection=%s isolation=%s", txID, connection, isolation)
+	return txID, nil
+}
+
+// lookupTx 查找一个活跃事务并刷新其最近使用时间；tx_id 不存在或已终止（被提交、
+// 回滚或空闲超时回收）时返回错误
+func (m *DBManager) lookupTx(txID string) (*txHandle, error) {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	h, ok := m.txs[txID]
+	if !ok {
+		return nil, fmt.Errorf("事务 %s 不存在或已结束", txID)
+	}
+	h.lastUsed = time.Now()
+	return h, nil
+}
+
+// takeTx 查找并立即移除一个活跃事务，供 CommitTx/RollbackTx 以及空闲回收
+// goroutine在结束事务前使用，防止同一事务被并发提交/回滚两次
+func (m *DBManager) takeTx(txID string) (*txHandle, error) {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	h, ok := m.txs[txID]
+	if !ok {
+		return nil, fmt.Errorf("事务 %s 不存在或已结束", txID)
+	}
+	delete(m.txs, txID)
+	return h, nil
+}
+
+// CommitTx 提交并移除一个事务
+func (m *DBManager) CommitTx(txID string) error {
+	h, err := m.takeTx(txID)
+	if err != nil {
+		return err
+	}
+	if err := h.tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	log.Printf("[TX COMMIT] tx_id=%s connection=%s", txID, h.connection)
+	return nil
+}
+
+// RollbackTx 回滚并移除一个事务
+func (m *DBManager) RollbackTx(txID string) error {
+	h, err := m.takeTx(txID)
+	if err != nil {
+		return err
+	}
+	if err := h.tx.Rollback(); err != nil {
+		return fmt.Errorf("回滚事务失败: %v", err)
+	}
+	log.Printf("[TX ROLLBACK] tx_id=%s connection=%s", txID, h.connection)
+	return nil
+}
+
+// TxConnection 返回 tx_id 对应事务所在的连接名，供调用方在批量执行前按该
+// 连接的权限校验每条语句
+func (m *DBManager) TxConnection(txID string) (string, error) {
+	h, err := m.lookupTx(txID)
+	if err != nil {
+		return "", err
+	}
+	return h.connection, nil
+}
+
+// Savepoint 在 tx_id 对应的已开启事务内创建一个命名保存点，供之后
+// RollbackToSavepoint 部分回滚而不终止整个事务
+func (m *DBManager) Savepoint(txID, name string) error {
+	h, err := m.lookupTx(txID)
+	if err != nil {
+		return err
+	}
+	if _, err := h.tx.ExecContext(context.Background(), "SAVEPOINT "+quoteIdent(name)); err != nil {
+		return fmt.Errorf("创建保存点失败: %v", err)
+	}
+	log.Printf("[TX SAVEPOINT] tx_id=%s connection=%s name=%s", txID, h.connection, name)
+	return nil
+}
+
+// RollbackToSavepoint 将 tx_id 对应的事务回滚到先前由 Savepoint 创建的保存点，
+// 事务本身保持开启，可以继续 QueryTx/ExecuteTx
+func (m *DBManager) RollbackToSavepoint(txID, name string) error {
+	h, err := m.lookupTx(txID)
+	if err != nil {
+		return err
+	}
+	if _, err := h.tx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+quoteIdent(name)); err != nil {
+		return fmt.Errorf("回滚到保存点失败: %v", err)
+	}
+	log.Printf("[TX ROLLBACK TO SAVEPOINT] tx_id=%s connection=%s name=%s", txID, h.connection, name)
+	return nil
+}
+
+// quoteIdent 用反引号引用一个标识符（保存点名），并转义其中出现的反引号；
+// 保存点名不支持占位符绑定，因此用这种方式而不是参数化查询传给驱动
+func quoteIdent(ident string) string {
+	return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+}
+
+// QueryTx 在 tx_id 对应的已开启事务内执行查询，结果按 DefaultMaxRows/
+// DefaultMaxBytes截断；需要自定义上限时用 QueryTxWithOptions。
+func (m *DBManager) QueryTx(txID, query string, args ...interface{}) (*QueryResult, error) {
+	return m.QueryTxWithOptions(txID, QueryOptions{}, query, args...)
+}
+
+// QueryTxWithOptions 在 tx_id 对应的已开启事务内执行查询，按 opts 的行数/字节数
+// 上限提前停止扫描
+func (m *DBManager) QueryTxWithOptions(txID string, opts QueryOptions, query string, args ...interface{}) (*QueryResult, error) {
+	h, err := m.lookupTx(txID)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result, err := runQuery(context.Background(), h.tx, opts, query, args...)
+	m.recordSlowQuery(h.connection, query, time.Since(start))
+	return result, err
+}
+
+// ExecuteTx 在 tx_id 对应的已开启事务内执行写操作。与 Execute 一样按
+// checkWritePermission 校验该连接的 AllowInsert/AllowUpdate/AllowDelete——
+// 事务内的写操作不能绕开非事务路径上的同一权限检查，否则一个只读连接只需先
+// mysql_begin 就能在事务里执行任意 INSERT/UPDATE/DELETE。
+func (m *DBManager) ExecuteTx(txID, query string, args ...interface{}) (*ExecuteResult, error) {
+	h, err := m.lookupTx(txID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkWritePermission(h.connection, query); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result, err := runExecute(context.Background(), h.tx, query, args...)
+	m.recordSlowQuery(h.connection, query, time.Since(start))
+	return result, err
+}
+
+// reapIdleTx 周期性地回滚并清理超过 TxIdleTimeout 未被使用的事务，直到
+// Close 关闭 stopReaper
+func (m *DBManager) reapIdleTx() {
+	ticker := time.NewTicker(reaperInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopReaper:
+			return
+		case <-ticker.C:
+			m.reapOnce()
+		}
+	}
+}
+
+func (m *DBManager) reapOnce() {
+	now := time.Now()
+
+	m.txMu.Lock()
+	var candidates []string
+	for txID, h := range m.txs {
+		if now.Sub(h.lastUsed) > TxIdleTimeout {
+			candidates = append(candidates, txID)
+		}
+	}
+	// 逐个重新确认过期状态并在同一把锁内删除，而不是先收集一份快照再释放锁。
+	// 否则在锁释放后、真正删除之前，一个并发的 QueryTx/ExecuteTx 可能已经
+	// lookupTx 刷新了 lastUsed 并开始在事务上执行语句，这里仍会把它当作过期
+	// 事务删除并回滚，导致一条正在执行的语句下面的事务被收割。
+	type expiredTx struct {
+		txID string
+		h    *txHandle
+	}
+	expired := make([]expiredTx, 0, len(candidates))
+	for _, txID := range candidates {
+		h, ok := m.txs[txID]
+		if !ok || now.Sub(h.lastUsed) <= TxIdleTimeout {
+			continue
+		}
+		delete(m.txs, txID)
+		expired = append(expired, expiredTx{txID, h})
+	}
+	m.txMu.Unlock()
+
+	for _, e := range expired {
+		if err := e.h.tx.Rollback(); err != nil {
+			log.Printf("[TX EXPIRE] tx_id=%s connection=%s rollback error: %v", e.txID, e.h.connection, err)
+			continue
+		}
+		log.Printf("[TX EXPIRE] tx_id=%s connection=%s idle timeout exceeded, rolled back", e.txID, e.h.connection)
+	}
+}
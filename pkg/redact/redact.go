@@ -0,0 +1,122 @@
+// Package redact 按配置的列名/正则规则对查询结果与SQL文本做脱敏，避免
+// password、身份证号等敏感字段未加处理就经由工具调用结果或审计日志外泄。
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/config"
+)
+
+// Mask 是命中脱敏规则的值被替换成的占位文本
+const Mask = "***REDACTED***"
+
+// Redactor 持有编译后的列名/正则规则
+type Redactor struct {
+	columns  map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// New 按 cfg 中声明的规则构建一个 Redactor。cfg 为空值（Columns、Patterns
+// 均未配置）时返回的 Redactor 各方法均是no-op，调用方无需额外判空。
+func New(cfg config.Redaction) (*Redactor, error) {
+	r := &Redactor{columns: make(map[string]bool, len(cfg.Columns))}
+	for _, col := range cfg.Columns {
+		r.columns[strings.ToLower(col)] = true
+	}
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的脱敏正则 %q: %v", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// ColumnSensitive 判断列名是否命中脱敏配置的列名规则（忽略大小写）。r为nil时
+// 始终返回false。
+func (r *Redactor) ColumnSensitive(column string) bool {
+	if r == nil {
+		return false
+	}
+	return r.columns[strings.ToLower(column)]
+}
+
+func (r *Redactor) matchesPattern(s string) bool {
+	for _, re := range r.patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskRows 原地替换按行排列的 rows 中命中脱敏列名或正则规则的值为 Mask，
+// 返回是否有任意值被替换。r为nil时是no-op，返回false。
+func (r *Redactor) MaskRows(columns []string, rows [][]interface{}) bool {
+	if r == nil {
+		return false
+	}
+	redacted := false
+	for _, row := range rows {
+		for i := range row {
+			if i >= len(columns) {
+				continue
+			}
+			if r.maskValue(columns[i], &row[i]) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+// MaskColumnar 原地替换按列排列的 rows（每个元素是一列在所有行上的取值）中
+// 命中脱敏列名或正则规则的值为 Mask，返回是否有任意值被替换。r为nil时是
+// no-op，返回false。
+func (r *Redactor) MaskColumnar(columns []string, rows [][]interface{}) bool {
+	if r == nil {
+		return false
+	}
+	redacted := false
+	for c, column := range rows {
+		if c >= len(columns) {
+			continue
+		}
+		for i := range column {
+			if r.maskValue(columns[c], &column[i]) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+func (r *Redactor) maskValue(column string, v *interface{}) bool {
+	if r.ColumnSensitive(column) {
+		*v = Mask
+		return true
+	}
+	if s, ok := (*v).(string); ok && r.matchesPattern(s) {
+		*v = Mask
+		return true
+	}
+	return false
+}
+
+// MaskSQL 把SQL文本中匹配脱敏正则的片段替换为 Mask，用于审计日志记录SQL时
+// 避免把字面量中的敏感值落盘。列名规则不适用于原始SQL文本，因为这里没有把
+// 列名与取值对应起来。r为nil时原样返回sql。
+func (r *Redactor) MaskSQL(sql string) string {
+	if r == nil {
+		return sql
+	}
+	masked := sql
+	for _, re := range r.patterns {
+		masked = re.ReplaceAllString(masked, Mask)
+	}
+	return masked
+}
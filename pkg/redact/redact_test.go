@@ -0,0 +1,126 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/config"
+)
+
+func TestNewNoopRedactor(t *testing.T) {
+	r, err := New(config.Redaction{})
+	if err != nil {
+		t.Fatalf("New(empty) error = %v", err)
+	}
+	if r.ColumnSensitive("password") {
+		t.Fatal("empty Redaction should not flag any column as sensitive")
+	}
+	rows := [][]interface{}{{"a", "b"}}
+	if redacted := r.MaskRows([]string{"x", "y"}, rows); redacted {
+		t.Fatal("empty Redaction should not redact anything")
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	_, err := New(config.Redaction{Patterns: []string{"("}})
+	if err == nil {
+		t.Fatal("expected error for invalid regex pattern")
+	}
+}
+
+func TestColumnSensitive(t *testing.T) {
+	r, err := New(config.Redaction{Columns: []string{"Password", "id_card"}})
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+	cases := []struct {
+		column string
+		want   bool
+	}{
+		{"password", true},
+		{"PASSWORD", true},
+		{"id_card", true},
+		{"name", false},
+	}
+	for _, tc := range cases {
+		if got := r.ColumnSensitive(tc.column); got != tc.want {
+			t.Errorf("ColumnSensitive(%q) = %v, want %v", tc.column, got, tc.want)
+		}
+	}
+
+	var nilRedactor *Redactor
+	if nilRedactor.ColumnSensitive("password") {
+		t.Fatal("nil Redactor should report no column as sensitive")
+	}
+}
+
+func TestMaskRows(t *testing.T) {
+	r, err := New(config.Redaction{
+		Columns:  []string{"password"},
+		Patterns: []string{`\d{11}`},
+	})
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+
+	columns := []string{"name", "password", "phone"}
+	rows := [][]interface{}{
+		{"alice", "secret", "13800000000"},
+		{"bob", "hunter2", "not-a-phone"},
+	}
+
+	redacted := r.MaskRows(columns, rows)
+	if !redacted {
+		t.Fatal("expected MaskRows to report a redaction")
+	}
+	if rows[0][1] != Mask || rows[1][1] != Mask {
+		t.Fatalf("password column not masked: %v", rows)
+	}
+	if rows[0][2] != Mask {
+		t.Fatalf("phone value matching the pattern not masked: %v", rows)
+	}
+	if rows[1][2] != "not-a-phone" {
+		t.Fatalf("value not matching the pattern should be left alone: %v", rows)
+	}
+	if rows[0][0] != "alice" || rows[1][0] != "bob" {
+		t.Fatalf("unrelated column should be untouched: %v", rows)
+	}
+}
+
+func TestMaskColumnar(t *testing.T) {
+	r, err := New(config.Redaction{Columns: []string{"password"}})
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+
+	columns := []string{"name", "password"}
+	columnar := [][]interface{}{
+		{"alice", "bob"},
+		{"secret1", "secret2"},
+	}
+	if redacted := r.MaskColumnar(columns, columnar); !redacted {
+		t.Fatal("expected MaskColumnar to report a redaction")
+	}
+	if columnar[1][0] != Mask || columnar[1][1] != Mask {
+		t.Fatalf("password column not masked: %v", columnar)
+	}
+	if columnar[0][0] != "alice" || columnar[0][1] != "bob" {
+		t.Fatalf("unrelated column should be untouched: %v", columnar)
+	}
+}
+
+func TestMaskSQL(t *testing.T) {
+	r, err := New(config.Redaction{Patterns: []string{`\d{11}`}})
+	if err != nil {
+		t.Fatalf("New error = %v", err)
+	}
+	got := r.MaskSQL("SELECT * FROM users WHERE phone = '13800000000'")
+	want := "SELECT * FROM users WHERE phone = '" + Mask + "'"
+	if got != want {
+		t.Fatalf("MaskSQL = %q, want %q", got, want)
+	}
+
+	var nilRedactor *Redactor
+	if got := nilRedactor.MaskSQL("SELECT 1"); got != "SELECT 1" {
+		t.Fatalf("nil Redactor should return sql unchanged, got %q", got)
+	}
+}
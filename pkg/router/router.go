@@ -0,0 +1,263 @@
+// Package router 实现了一个轻量级的SQL分片路由层，用于将面向单张逻辑表的SQL
+// 按分片规则分发到多个物理数据库连接（由 pkg/db.DBManager 管理），并合并结果。
+//
+// 为了不引入 vitess/pingcap 级别的完整SQL解析器，这里使用基于正则表达式的
+// 启发式解析：只识别 FROM/INTO/UPDATE 之后的表名，以及分片键的等值条件
+// （key = value）。无法识别分片键的写操作会被拒绝，读操作会广播到所有分片。
+package router
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/config"
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/db"
+)
+
+// ErrNoCriteria 表示写操作未能从SQL中识别出分片键，无法安全地路由到单个分片
+var ErrNoCriteria = errors.New("无法从SQL中识别分片键，写操作被拒绝")
+
+// Rule 是 config.ShardRule 的别名，分片规则的来源始终是应用配置
+type Rule = config.ShardRule
+
+// Plan 描述一次查询/写入的路由计划
+type Plan struct {
+	Table            string   `json:"table"`
+	ShardKeyValue    string   `json:"shard_key_value,omitempty"`
+	RouteNodeIndexs  []int    `json:"route_node_indexs"`
+	RouteTableIndexs []int    `json:"route_table_indexs,omitempty"`
+	Nodes            []string `json:"nodes"`
+	Broadcast        bool     `json:"broadcast"`
+	SQL              string   `json:"sql"`
+}
+
+// Router 依据配置的分片规则计算并执行跨节点的SQL路由
+type Router struct {
+	rules     map[string]Rule
+	dbManager *db.DBManager
+}
+
+// NewRouter 创建一个分片路由器，rules 以表名（小写）为单位声明分片规则
+func NewRouter(rules []Rule, dbManager *db.DBManager) *Router {
+	byTable := make(map[string]Rule, len(rules))
+	for _, r := range rules {
+		byTable[strings.ToLower(r.Table)] = r
+	}
+	return &Router{rules: byTable, dbManager: dbManager}
+}
+
+// HasRule 判断某张表是否配置了分片规则
+func (r *Router) HasRule(table string) bool {
+	_, ok := r.rules[strings.ToLower(table)]
+	return ok
+}
+
+var (
+	tableRe = regexp.MustCompile("(?i)\\b(?:FROM|INTO|UPDATE)\\s+`?([a-zA-Z_][a-zA-Z0-9_]*)`?")
+)
+
+// ExtractTable 从SQL中提取目标表名（FROM/INTO/UPDATE 之后的第一个标识符）
+func ExtractTable(sql string) string {
+	m := tableRe.FindStringSubmatch(sql)
+	if len(m) < 2 {
+		return ""
+	}
+	return m[1]
+}
+
+// extractShardKeyValue 从SQL中提取分片键的等值条件，未出现时返回空字符串
+func extractShardKeyValue(sql, shardKey string) string {
+	re := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(shardKey) + `\s*=\s*'?([a-zA-Z0-9_\-]+)'?`)
+	m := re.FindStringSubmatch(sql)
+	if len(m) < 2 {
+		return ""
+	}
+	return m[1]
+}
+
+func isWriteStatement(sql string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(sql))
+	for _, prefix := range []string{"INSERT", "UPDATE", "DELETE", "REPLACE"} {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Explain 计算给定SQL的路由计划，但不执行
+func (r *Router) Explain(sql string) (*Plan, error) {
+	table := ExtractTable(sql)
+	rule, ok := r.rules[strings.ToLower(table)]
+	if !ok {
+		return nil, fmt.Errorf("表 %s 未配置分片规则", table)
+	}
+
+	keyValue := extractShardKeyValue(sql, rule.ShardKey)
+	if keyValue == "" {
+		if isWriteStatement(sql) {
+			return nil, ErrNoCriteria
+		}
+		// 读操作且无法确定分片键：广播到所有分片。range 规则的节点声明在各
+		// 区间的 Nodes 上，因此从各区间收集（去重），而不是只取顶层 Nodes
+		nodes := rule.Nodes
+		if rule.ShardFunc == config.ShardFuncRange {
+			nodes = nil
+			seen := make(map[string]bool)
+			for _, bound := range rule.Ranges {
+				for _, node := range bound.Nodes {
+					if !seen[node] {
+						seen[node] = true
+						nodes = append(nodes, node)
+					}
+				}
+			}
+		}
+		indexes := make([]int, len(nodes))
+		for i := range nodes {
+			indexes[i] = i
+		}
+		return &Plan{
+			Table:           table,
+			RouteNodeIndexs: indexes,
+			Nodes:           nodes,
+			Broadcast:       true,
+			SQL:             sql,
+		}, nil
+	}
+
+	idx, err := routeIndex(rule, keyValue)
+	if err != nil {
+		return nil, err
+	}
+
+	// range 分片函数下，idx 是命中的区间在 rule.Ranges 中的下标，节点要从该
+	// 区间自己声明的 Nodes 里取，而不是顶层 rule.Nodes（区间的 Nodes 与顶层
+	// Nodes 是两个独立的节点列表，range规则的顶层 Nodes 甚至可以留空）；
+	// mod/hash 函数下 idx 就是 rule.Nodes 的下标。
+	var nodes []string
+	if rule.ShardFunc == config.ShardFuncRange {
+		bound := rule.Ranges[idx]
+		if len(bound.Nodes) == 0 {
+			return nil, fmt.Errorf("表 %s 的分片区间（max=%d）未配置任何节点", table, bound.Max)
+		}
+		nodes = bound.Nodes
+	} else {
+		nodes = []string{rule.Nodes[idx]}
+	}
+
+	return &Plan{
+		Table:           table,
+		ShardKeyValue:   keyValue,
+		RouteNodeIndexs: []int{idx},
+		Nodes:           nodes,
+		SQL:             sql,
+	}, nil
+}
+
+// routeIndex 根据分片函数计算分片键对应的节点下标
+func routeIndex(r Rule, keyValue string) (int, error) {
+	// range 规则的节点声明在各区间自己的 Nodes 上，顶层 Nodes 可以留空，因此
+	// 这里的"未配置任何节点"校验只适用于按下标选取顶层 Nodes 的 mod/hash。
+	if r.ShardFunc != config.ShardFuncRange && len(r.Nodes) == 0 {
+		return 0, fmt.Errorf("表 %s 的分片规则未配置任何节点", r.Table)
+	}
+
+	switch r.ShardFunc {
+	case config.ShardFuncRange:
+		n, err := strconv.ParseInt(keyValue, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("分片键 %s 不是合法的整数: %v", keyValue, err)
+		}
+		for i, bound := range r.Ranges {
+			if n <= bound.Max {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("分片键 %d 未匹配任何range区间", n)
+	case config.ShardFuncMod:
+		n, err := strconv.ParseInt(keyValue, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("分片键 %s 不是合法的整数: %v", keyValue, err)
+		}
+		// Go 的 % 对负数取模会返回负数（如 -5 % 3 == -2），直接用作 r.Nodes 的
+		// 下标会越界panic；加一个模再取一次模把结果规整到 [0, m) 内。
+		m := int64(len(r.Nodes))
+		return int(((n % m) + m) % m), nil
+	default: // ShardFuncHash
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(keyValue))
+		return int(h.Sum32() % uint32(len(r.Nodes))), nil
+	}
+}
+
+// Query 按路由计划将SQL分发到一个或多个分片并合并行结果
+func (r *Router) Query(sql string, args ...interface{}) (*db.QueryResult, *Plan, error) {
+	plan, err := r.Explain(sql)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shardResults := make([]*db.QueryResult, len(plan.Nodes))
+	shardErrors := make([]error, len(plan.Nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range plan.Nodes {
+		wg.Add(1)
+		go func(i int, node string) {
+			defer wg.Done()
+			shardResults[i], shardErrors[i] = r.dbManager.Query(node, sql, args...)
+		}(i, node)
+	}
+	wg.Wait()
+
+	merged := &db.QueryResult{Rows: make([][]interface{}, 0)}
+	for i, err := range shardErrors {
+		if err != nil {
+			return nil, plan, fmt.Errorf("分片 %s 查询失败: %v", plan.Nodes[i], err)
+		}
+		if merged.Columns == nil {
+			merged.Columns = shardResults[i].Columns
+		}
+		merged.Rows = append(merged.Rows, shardResults[i].Rows...)
+	}
+	return merged, plan, nil
+}
+
+// Execute 按路由计划将写操作分发到一个或多个分片，汇总影响行数和最后插入ID
+func (r *Router) Execute(sql string, args ...interface{}) (*db.ExecuteResult, *Plan, error) {
+	plan, err := r.Explain(sql)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shardResults := make([]*db.ExecuteResult, len(plan.Nodes))
+	shardErrors := make([]error, len(plan.Nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range plan.Nodes {
+		wg.Add(1)
+		go func(i int, node string) {
+			defer wg.Done()
+			shardResults[i], shardErrors[i] = r.dbManager.Execute(node, sql, args...)
+		}(i, node)
+	}
+	wg.Wait()
+
+	merged := &db.ExecuteResult{}
+	for i, err := range shardErrors {
+		if err != nil {
+			return nil, plan, fmt.Errorf("分片 %s 执行失败: %v", plan.Nodes[i], err)
+		}
+		merged.RowsAffected += shardResults[i].RowsAffected
+		if shardResults[i].LastInsertID != 0 {
+			merged.LastInsertID = shardResults[i].LastInsertID
+		}
+	}
+	return merged, plan, nil
+}
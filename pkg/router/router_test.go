@@ -0,0 +1,126 @@
+package router
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/config"
+)
+
+func TestExplainBroadcastRead(t *testing.T) {
+	r := NewRouter([]Rule{{
+		Table:     "orders",
+		ShardKey:  "id",
+		ShardFunc: config.ShardFuncHash,
+		Nodes:     []string{"n0", "n1"},
+	}}, nil)
+
+	plan, err := r.Explain("SELECT * FROM orders")
+	if err != nil {
+		t.Fatalf("Explain error = %v", err)
+	}
+	if !plan.Broadcast {
+		t.Fatal("expected a broadcast plan when the shard key can't be determined")
+	}
+	if len(plan.Nodes) != 2 {
+		t.Fatalf("expected broadcast to all nodes, got %v", plan.Nodes)
+	}
+}
+
+func TestExplainWriteWithoutCriteriaRejected(t *testing.T) {
+	r := NewRouter([]Rule{{
+		Table:     "orders",
+		ShardKey:  "id",
+		ShardFunc: config.ShardFuncHash,
+		Nodes:     []string{"n0", "n1"},
+	}}, nil)
+
+	_, err := r.Explain("UPDATE orders SET status = 'done'")
+	if !errors.Is(err, ErrNoCriteria) {
+		t.Fatalf("Explain error = %v, want ErrNoCriteria", err)
+	}
+}
+
+func TestExplainMod(t *testing.T) {
+	r := NewRouter([]Rule{{
+		Table:     "orders",
+		ShardKey:  "id",
+		ShardFunc: config.ShardFuncMod,
+		Nodes:     []string{"n0", "n1", "n2"},
+	}}, nil)
+
+	plan, err := r.Explain("SELECT * FROM orders WHERE id = 7")
+	if err != nil {
+		t.Fatalf("Explain error = %v", err)
+	}
+	want := "n1" // 7 % 3 == 1
+	if len(plan.Nodes) != 1 || plan.Nodes[0] != want {
+		t.Fatalf("Explain Nodes = %v, want [%s]", plan.Nodes, want)
+	}
+}
+
+func TestExplainModNegativeKey(t *testing.T) {
+	r := NewRouter([]Rule{{
+		Table:     "orders",
+		ShardKey:  "id",
+		ShardFunc: config.ShardFuncMod,
+		Nodes:     []string{"n0", "n1", "n2"},
+	}}, nil)
+
+	plan, err := r.Explain("SELECT * FROM orders WHERE id = -5")
+	if err != nil {
+		t.Fatalf("Explain error = %v", err)
+	}
+	want := "n1" // -5 mod 3 normalized to 1
+	if len(plan.Nodes) != 1 || plan.Nodes[0] != want {
+		t.Fatalf("Explain Nodes = %v, want [%s]", plan.Nodes, want)
+	}
+}
+
+func TestExplainRangeUsesBoundNodes(t *testing.T) {
+	r := NewRouter([]Rule{{
+		Table:     "orders",
+		ShardKey:  "id",
+		ShardFunc: config.ShardFuncRange,
+		Ranges: []config.ShardRangeBound{
+			{Max: 100, Nodes: []string{"n0"}},
+			{Max: 200, Nodes: []string{"n1"}},
+		},
+	}}, nil)
+
+	plan, err := r.Explain("SELECT * FROM orders WHERE id = 50")
+	if err != nil {
+		t.Fatalf("Explain error = %v", err)
+	}
+	if len(plan.Nodes) != 1 || plan.Nodes[0] != "n0" {
+		t.Fatalf("Explain Nodes = %v, want [n0]", plan.Nodes)
+	}
+
+	plan, err = r.Explain("SELECT * FROM orders WHERE id = 150")
+	if err != nil {
+		t.Fatalf("Explain error = %v", err)
+	}
+	if len(plan.Nodes) != 1 || plan.Nodes[0] != "n1" {
+		t.Fatalf("Explain Nodes = %v, want [n1]", plan.Nodes)
+	}
+}
+
+func TestExplainRangeNoMatchingBound(t *testing.T) {
+	r := NewRouter([]Rule{{
+		Table:     "orders",
+		ShardKey:  "id",
+		ShardFunc: config.ShardFuncRange,
+		Ranges:    []config.ShardRangeBound{{Max: 100, Nodes: []string{"n0"}}},
+	}}, nil)
+
+	if _, err := r.Explain("SELECT * FROM orders WHERE id = 500"); err == nil {
+		t.Fatal("expected an error when the key matches no range bound")
+	}
+}
+
+func TestExplainUnknownTable(t *testing.T) {
+	r := NewRouter(nil, nil)
+	if _, err := r.Explain("SELECT * FROM orders WHERE id = 1"); err == nil {
+		t.Fatal("expected an error for a table with no shard rule")
+	}
+}
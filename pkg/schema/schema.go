@@ -0,0 +1,333 @@
+// Package schema 提供基于 information_schema 的数据库结构自省，并用TTL缓存
+// 查询结果，避免LLM反复发起 DESCRIBE/SHOW 请求时对数据库造成压力。
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blanplan-ai/ai2mysql-mcp-server/pkg/db"
+)
+
+// DefaultTTL 是缓存条目未显式配置TTL时使用的默认失效时间
+const DefaultTTL = 5 * time.Minute
+
+// Database 描述一个数据库（information_schema.schemata 的一行）
+type Database struct {
+	Name string `json:"name"`
+}
+
+// Table 描述一张表
+type Table struct {
+	Name    string `json:"name"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Column 描述一张表的一个列
+type Column struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Key      string  `json:"key,omitempty"`
+	Default  *string `json:"default,omitempty"`
+	Extra    string  `json:"extra,omitempty"`
+}
+
+// Index 描述索引中的一列
+type Index struct {
+	Name       string `json:"name"`
+	Column     string `json:"column"`
+	SeqInIndex int    `json:"seq_in_index"`
+	NonUnique  bool   `json:"non_unique"`
+}
+
+// ForeignKey 描述一条外键约束
+type ForeignKey struct {
+	Name             string `json:"name"`
+	Column           string `json:"column"`
+	ReferencedTable  string `json:"referenced_table"`
+	ReferencedColumn string `json:"referenced_column"`
+}
+
+// CreateTable 持有 SHOW CREATE TABLE 返回的建表语句
+type CreateTable struct {
+	Table       string `json:"table"`
+	CreateTable string `json:"create_table"`
+}
+
+type cacheKey struct {
+	connection string
+	database   string
+	object     string
+}
+
+type cacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// Cache 按 (connection, database, object) 缓存 information_schema 查询结果
+type Cache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[cacheKey]cacheEntry
+	dbManager *db.DBManager
+}
+
+// NewCache 创建一个结构自省缓存；ttl <= 0 时使用 DefaultTTL
+func NewCache(dbManager *db.DBManager, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Cache{
+		ttl:       ttl,
+		entries:   make(map[cacheKey]cacheEntry),
+		dbManager: dbManager,
+	}
+}
+
+// Invalidate 清除缓存；database/object 留空时按前缀清除更大范围
+// （例如只传 connection 可清空该连接下的全部缓存）
+func (c *Cache) Invalidate(connection, database, object string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if connection != "" && key.connection != connection {
+			continue
+		}
+		if database != "" && key.database != database {
+			continue
+		}
+		if object != "" && key.object != object {
+			continue
+		}
+		delete(c.entries, key)
+	}
+}
+
+func (c *Cache) getOrLoad(connection, database, object string, load func(*sql.DB) (interface{}, error)) (interface{}, error) {
+	key := cacheKey{connection: connection, database: database, object: object}
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expiresAt) {
+		c.mu.Unlock()
+		return entry.value, nil
+	}
+	c.mu.Unlock()
+
+	sqlDB, err := c.dbManager.GetDB(connection)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := load(sqlDB)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{value: value, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return value, nil
+}
+
+// ListDatabases 返回连接上可见的数据库列表
+func (c *Cache) ListDatabases(connection string) ([]Database, error) {
+	value, err := c.getOrLoad(connection, "", "databases", func(sqlDB *sql.DB) (interface{}, error) {
+		rows, err := sqlDB.Query("SELECT schema_name FROM information_schema.schemata ORDER BY schema_name")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		databases := make([]Database, 0)
+		for rows.Next() {
+			var d Database
+			if err := rows.Scan(&d.Name); err != nil {
+				return nil, err
+			}
+			databases = append(databases, d)
+		}
+		return databases, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]Database), nil
+}
+
+// ListTables 返回指定数据库中的表，pattern 为空时返回全部，否则按 LIKE 过滤表名
+func (c *Cache) ListTables(connection, database, pattern string) ([]Table, error) {
+	object := "tables"
+	if pattern != "" {
+		object = "tables:" + pattern
+	}
+
+	value, err := c.getOrLoad(connection, database, object, func(sqlDB *sql.DB) (interface{}, error) {
+		query := "SELECT table_name, COALESCE(table_comment, '') FROM information_schema.tables WHERE table_schema = ?"
+		args := []interface{}{database}
+		if pattern != "" {
+			query += " AND table_name LIKE ?"
+			args = append(args, pattern)
+		}
+		query += " ORDER BY table_name"
+
+		rows, err := sqlDB.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		tables := make([]Table, 0)
+		for rows.Next() {
+			var t Table
+			if err := rows.Scan(&t.Name, &t.Comment); err != nil {
+				return nil, err
+			}
+			tables = append(tables, t)
+		}
+		return tables, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]Table), nil
+}
+
+// DescribeTable 返回一张表的列定义
+func (c *Cache) DescribeTable(connection, database, table string) ([]Column, error) {
+	value, err := c.getOrLoad(connection, database, "columns:"+table, func(sqlDB *sql.DB) (interface{}, error) {
+		rows, err := sqlDB.Query(
+			`SELECT column_name, column_type, is_nullable, column_key, column_default, extra
+			 FROM information_schema.columns
+			 WHERE table_schema = ? AND table_name = ?
+			 ORDER BY ordinal_position`,
+			database, table,
+		)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		columns := make([]Column, 0)
+		for rows.Next() {
+			var (
+				col        Column
+				isNullable string
+				defaultVal sql.NullString
+			)
+			if err := rows.Scan(&col.Name, &col.Type, &isNullable, &col.Key, &defaultVal, &col.Extra); err != nil {
+				return nil, err
+			}
+			col.Nullable = isNullable == "YES"
+			if defaultVal.Valid {
+				col.Default = &defaultVal.String
+			}
+			columns = append(columns, col)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("表 %s.%s 不存在或没有列", database, table)
+		}
+		return columns, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]Column), nil
+}
+
+// ShowIndexes 返回一张表的索引定义
+func (c *Cache) ShowIndexes(connection, database, table string) ([]Index, error) {
+	value, err := c.getOrLoad(connection, database, "indexes:"+table, func(sqlDB *sql.DB) (interface{}, error) {
+		rows, err := sqlDB.Query(
+			`SELECT index_name, column_name, seq_in_index, non_unique
+			 FROM information_schema.statistics
+			 WHERE table_schema = ? AND table_name = ?
+			 ORDER BY index_name, seq_in_index`,
+			database, table,
+		)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		indexes := make([]Index, 0)
+		for rows.Next() {
+			var idx Index
+			if err := rows.Scan(&idx.Name, &idx.Column, &idx.SeqInIndex, &idx.NonUnique); err != nil {
+				return nil, err
+			}
+			indexes = append(indexes, idx)
+		}
+		return indexes, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]Index), nil
+}
+
+// ShowForeignKeys 返回一张表的外键约束
+func (c *Cache) ShowForeignKeys(connection, database, table string) ([]ForeignKey, error) {
+	value, err := c.getOrLoad(connection, database, "foreign_keys:"+table, func(sqlDB *sql.DB) (interface{}, error) {
+		rows, err := sqlDB.Query(
+			`SELECT constraint_name, column_name, referenced_table_name, referenced_column_name
+			 FROM information_schema.key_column_usage
+			 WHERE table_schema = ? AND table_name = ? AND referenced_table_name IS NOT NULL
+			 ORDER BY constraint_name`,
+			database, table,
+		)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		foreignKeys := make([]ForeignKey, 0)
+		for rows.Next() {
+			var fk ForeignKey
+			if err := rows.Scan(&fk.Name, &fk.Column, &fk.ReferencedTable, &fk.ReferencedColumn); err != nil {
+				return nil, err
+			}
+			foreignKeys = append(foreignKeys, fk)
+		}
+		return foreignKeys, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]ForeignKey), nil
+}
+
+// ShowCreateTable 返回 SHOW CREATE TABLE 给出的建表语句。该语句不支持占位符
+// 绑定表名，因此用反引号引用 database/table，并对其中出现的反引号做转义。
+func (c *Cache) ShowCreateTable(connection, database, table string) (*CreateTable, error) {
+	value, err := c.getOrLoad(connection, database, "create_table:"+table, func(sqlDB *sql.DB) (interface{}, error) {
+		query := fmt.Sprintf("SHOW CREATE TABLE %s.%s", quoteIdent(database), quoteIdent(table))
+		row := sqlDB.QueryRow(query)
+
+		var ct CreateTable
+		if err := row.Scan(&ct.Table, &ct.CreateTable); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("表 %s.%s 不存在", database, table)
+			}
+			return nil, err
+		}
+		return &ct, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*CreateTable), nil
+}
+
+// quoteIdent 用反引号引用一个标识符，并转义其中出现的反引号
+func quoteIdent(ident string) string {
+	return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+}
@@ -0,0 +1,236 @@
+// Package sqlsafety 基于 xwb1989/sqlparser 对SQL语句做AST级别的安全校验，
+// 取代容易被注释、子查询等手法绕过的字符串前缀判断。
+//
+// 该解析器（2018年的vitess分支）本身不支持 WITH/CTE、GRANT、LOAD DATA INFILE、
+// SELECT ... INTO OUTFILE 语法，也不支持在一次 Parse 调用中塞入分号分隔的多条
+// 语句，因此形如 `WITH x AS (DELETE ...) SELECT ...`、`GRANT ...`、
+// `LOAD DATA INFILE ...`、`SELECT ... INTO OUTFILE ...` 或
+// `SELECT 1; DROP TABLE x` 这样的拼接/注入语句会在 sqlparser.Parse 阶段直接
+// 报错而被拒绝，无需额外处理。SET GLOBAL 能正常解析，因此在 CheckWriteAllowed
+// 中显式拒绝。
+package sqlsafety
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xwb1989/sqlparser"
+)
+
+// DefaultMaxRows 是未显式配置 max_rows 时只读查询的默认行数上限
+const DefaultMaxRows = 1000
+
+// ErrForbiddenStatement 表示语句类型或其子查询不满足安全策略
+var ErrForbiddenStatement = errors.New("该语句不被安全策略允许")
+
+// CheckReadOnly 解析sql并校验其是否为只读语句（SELECT/SHOW/DESCRIBE/EXPLAIN），
+// 并递归检查语句树中是否混入了DML节点。校验通过时返回解析得到的AST，供调用方
+// 在执行前用 ApplyLimit 改写 SQL。
+func CheckReadOnly(sql string) (sqlparser.Statement, error) {
+	stmt, err := sqlparser.Parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("SQL解析失败: %v", err)
+	}
+
+	switch stmt.(type) {
+	case *sqlparser.Select, *sqlparser.Union, *sqlparser.ParenSelect, *sqlparser.Show, *sqlparser.OtherRead:
+		// 只读语句，放行
+	default:
+		return nil, fmt.Errorf("%w: 只允许 SELECT/SHOW/DESCRIBE/EXPLAIN 语句", ErrForbiddenStatement)
+	}
+
+	if err := rejectNestedDML(stmt); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// CheckWriteAllowed 解析sql并基于AST拒绝 DROP/TRUNCATE/ALTER、SET GLOBAL 等
+// 危险的DDL/管理语句，取代原先对 "DROP"/"TRUNCATE" 的字符串前缀判断。校验通过
+// 时返回解析得到的AST，供调用方用 TablesReferenced 做表级权限校验。
+// INSERT/UPDATE/DELETE等常规写操作放行，由 db.DBManager 按连接权限继续做进一
+// 步校验。
+func CheckWriteAllowed(sql string) (sqlparser.Statement, error) {
+	stmt, err := sqlparser.Parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("SQL解析失败: %v", err)
+	}
+
+	switch s := stmt.(type) {
+	case *sqlparser.DDL:
+		switch s.Action {
+		case sqlparser.DropStr, sqlparser.AlterStr, sqlparser.TruncateStr:
+			return nil, fmt.Errorf("%w: 不允许 %s 操作", ErrForbiddenStatement, strings.ToUpper(s.Action))
+		}
+	case *sqlparser.DBDDL:
+		return nil, fmt.Errorf("%w: 不允许 CREATE/DROP DATABASE 操作", ErrForbiddenStatement)
+	case *sqlparser.Set:
+		if s.Scope == sqlparser.GlobalStr {
+			return nil, fmt.Errorf("%w: 不允许 SET GLOBAL 操作", ErrForbiddenStatement)
+		}
+	case *sqlparser.OtherAdmin:
+		// 该解析器把 REPAIR/OPTIMIZE 等管理语句统一归为 OtherAdmin，无法识别
+		// 具体动作，因此一律拒绝
+		return nil, fmt.Errorf("%w: 不允许管理类语句", ErrForbiddenStatement)
+	}
+
+	if err := rejectNestedDML(stmt); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// WriteKind 标识一条已解析语句对应的写操作种类，供调用方按连接权限校验
+// INSERT/UPDATE/DELETE——从语句的AST节点类型分类，而不是从SQL文本的前缀
+// 判断（前者不会被一段打头的注释或多余空白绕过）。
+type WriteKind int
+
+const (
+	// WriteOther 表示语句不是 INSERT/UPDATE/DELETE（例如 SELECT、SET），不受
+	// 这三个权限位约束。REPLACE 与 INSERT 共用同一AST节点，归为 WriteInsert
+	WriteOther WriteKind = iota
+	WriteInsert
+	WriteUpdate
+	WriteDelete
+)
+
+// ClassifyWrite 解析sql并返回其对应的 WriteKind 及解析得到的AST。
+func ClassifyWrite(sql string) (sqlparser.Statement, WriteKind, error) {
+	stmt, err := sqlparser.Parse(sql)
+	if err != nil {
+		return nil, WriteOther, fmt.Errorf("SQL解析失败: %v", err)
+	}
+	switch stmt.(type) {
+	case *sqlparser.Insert:
+		return stmt, WriteInsert, nil
+	case *sqlparser.Update:
+		return stmt, WriteUpdate, nil
+	case *sqlparser.Delete:
+		return stmt, WriteDelete, nil
+	default:
+		return stmt, WriteOther, nil
+	}
+}
+
+// rejectNestedDML 递归遍历AST，拒绝语句树中出现的 INSERT/UPDATE/DELETE 节点。
+// 当前语法不允许DML出现在子查询位置，这里的检查是面向解析器未来支持
+// CTE/存储过程等扩展语法的纵深防御。
+func rejectNestedDML(stmt sqlparser.Statement) error {
+	// sqlparser.Walk 也会把 stmt 自身作为第一个访问的节点，而 stmt 本身就
+	// 可能是一条被 CheckWriteAllowed 放行的顶层 INSERT/UPDATE/DELETE——这里
+	// 只需要拒绝出现在子树中的DML，所以显式跳过根节点。
+	var root sqlparser.SQLNode = stmt
+	var forbidden error
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if node == root {
+			return true, nil
+		}
+		switch node.(type) {
+		case *sqlparser.Insert, *sqlparser.Update, *sqlparser.Delete:
+			forbidden = fmt.Errorf("%w: 子查询中不允许包含写操作", ErrForbiddenStatement)
+			return false, nil
+		}
+		return true, nil
+	}, stmt)
+	return forbidden
+}
+
+// ApplyLimit 在AST层面为没有 LIMIT 子句的 SELECT/UNION 语句追加
+// "LIMIT maxRows+1"，并返回改写后的SQL字符串；已经带有 LIMIT 或不支持
+// LIMIT 的语句原样返回。多取一行是为了让调用方能在结果行数超过 maxRows
+// 时判断出 Truncated，而无需多发一次 COUNT 查询。
+func ApplyLimit(stmt sqlparser.Statement, maxRows int) string {
+	limit := &sqlparser.Limit{Rowcount: sqlparser.NewIntVal([]byte(strconv.Itoa(maxRows + 1)))}
+
+	switch s := stmt.(type) {
+	case *sqlparser.Select:
+		if s.Limit == nil {
+			s.Limit = limit
+		}
+	case *sqlparser.Union:
+		if s.Limit == nil {
+			s.Limit = limit
+		}
+	}
+	return sqlparser.String(stmt)
+}
+
+// ApplyPage 在AST层面为 SELECT/UNION 语句设置 "LIMIT pageSize+1 OFFSET offset"，
+// 覆盖语句中原有的 LIMIT（与只在缺失时填充的 ApplyLimit 不同，分页需要每页都
+// 显式控制 LIMIT/OFFSET），并返回改写后的SQL字符串。多取一行是为了让调用方能
+// 判断是否还有下一页而无需多发一次 COUNT 查询。不支持 LIMIT 子句的语句类型
+// （如SHOW）返回错误。
+func ApplyPage(stmt sqlparser.Statement, pageSize, offset int) (string, error) {
+	limit := &sqlparser.Limit{
+		Offset:   sqlparser.NewIntVal([]byte(strconv.Itoa(offset))),
+		Rowcount: sqlparser.NewIntVal([]byte(strconv.Itoa(pageSize + 1))),
+	}
+
+	switch s := stmt.(type) {
+	case *sqlparser.Select:
+		s.Limit = limit
+	case *sqlparser.Union:
+		s.Limit = limit
+	default:
+		return "", fmt.Errorf("该语句不支持分页")
+	}
+	return sqlparser.String(stmt), nil
+}
+
+// SelectsStar 判断语句是否包含裸露的 "*" 选择项（含 t.* 这类限定形式），供
+// 调用方判断一次 SELECT 是否可能意外返回了配置为脱敏的列，而不是调用方显式
+// 点名要取的列。
+func SelectsStar(stmt sqlparser.Statement) bool {
+	star := false
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		// 只看各 SELECT（含子查询）的选择项本身，COUNT(*) 这类函数参数中的
+		// "*" 不算
+		sel, ok := node.(*sqlparser.Select)
+		if !ok {
+			return true, nil
+		}
+		for _, expr := range sel.SelectExprs {
+			if _, ok := expr.(*sqlparser.StarExpr); ok {
+				star = true
+				return false, nil
+			}
+		}
+		return true, nil
+	}, stmt)
+	return star
+}
+
+// TablesReferenced 返回语句直接或间接（JOIN、子查询）引用的表名，按首次出现
+// 顺序去重，供调用方做表级允许/禁止名单校验。
+func TablesReferenced(stmt sqlparser.Statement) []string {
+	var tables []string
+	seen := make(map[string]bool)
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node.(type) {
+		case *sqlparser.ColName, *sqlparser.StarExpr:
+			// 列名/t.* 的限定符可能是表别名而非表名，不计入
+			return false, nil
+		}
+		t, ok := node.(sqlparser.TableName)
+		if !ok || t.IsEmpty() {
+			return true, nil
+		}
+		name := t.Name.String()
+		if !seen[name] {
+			seen[name] = true
+			tables = append(tables, name)
+		}
+		return true, nil
+	}, stmt)
+	return tables
+}
+
+// Truncate 在行数超过 maxRows 时截断为 maxRows 行，并返回是否发生了截断。
+func Truncate(rows [][]interface{}, maxRows int) ([][]interface{}, bool) {
+	if len(rows) > maxRows {
+		return rows[:maxRows], true
+	}
+	return rows, false
+}
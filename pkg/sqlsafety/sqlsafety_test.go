@@ -0,0 +1,145 @@
+package sqlsafety
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	cases := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{"select", "SELECT id FROM users WHERE id = 1", false},
+		{"show", "SHOW TABLES", false},
+		{"describe", "DESCRIBE users", false},
+		{"insert rejected", "INSERT INTO users (id) VALUES (1)", true},
+		{"update rejected", "UPDATE users SET name = 'x'", true},
+		{"delete rejected", "DELETE FROM users", true},
+		{"unparsable", "SELEC * FROM users", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CheckReadOnly(tc.sql)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CheckReadOnly(%q) error = %v, wantErr %v", tc.sql, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckWriteAllowed(t *testing.T) {
+	cases := []struct {
+		name    string
+		sql     string
+		wantErr bool
+	}{
+		{"insert", "INSERT INTO users (id) VALUES (1)", false},
+		{"update", "UPDATE users SET name = 'x' WHERE id = 1", false},
+		{"delete", "DELETE FROM users WHERE id = 1", false},
+		{"drop rejected", "DROP TABLE users", true},
+		{"truncate rejected", "TRUNCATE TABLE users", true},
+		{"alter rejected", "ALTER TABLE users ADD COLUMN x INT", true},
+		{"create database rejected", "CREATE DATABASE x", true},
+		{"set global rejected", "SET GLOBAL max_connections = 100", true},
+		{"set session allowed", "SET SESSION sql_mode = ''", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CheckWriteAllowed(tc.sql)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CheckWriteAllowed(%q) error = %v, wantErr %v", tc.sql, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestClassifyWrite(t *testing.T) {
+	cases := []struct {
+		name string
+		sql  string
+		want WriteKind
+	}{
+		{"insert", "INSERT INTO users (id) VALUES (1)", WriteInsert},
+		{"leading comment insert", "/*x*/ INSERT INTO users (id) VALUES (1)", WriteInsert},
+		{"update", "UPDATE users SET name = 'x' WHERE id = 1", WriteUpdate},
+		{"delete", "DELETE FROM users WHERE id = 1", WriteDelete},
+		{"select is other", "SELECT 1", WriteOther},
+		// REPLACE shares the *sqlparser.Insert AST node (Action: "replace"),
+		// so it classifies as an insert and is governed by AllowInsert.
+		{"replace classifies as insert", "REPLACE INTO users (id) VALUES (1)", WriteInsert},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, kind, err := ClassifyWrite(tc.sql)
+			if err != nil {
+				t.Fatalf("ClassifyWrite(%q) error = %v", tc.sql, err)
+			}
+			if kind != tc.want {
+				t.Fatalf("ClassifyWrite(%q) = %v, want %v", tc.sql, kind, tc.want)
+			}
+		})
+	}
+
+	if _, _, err := ClassifyWrite("SELEC * FROM x"); err == nil {
+		t.Fatal("expected parse error for unparsable SQL")
+	}
+}
+
+func TestSelectsStar(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT * FROM users", true},
+		{"SELECT u.* FROM users u", true},
+		{"SELECT id, name FROM users", false},
+	}
+	for _, tc := range cases {
+		stmt, err := CheckReadOnly(tc.sql)
+		if err != nil {
+			t.Fatalf("CheckReadOnly(%q) error = %v", tc.sql, err)
+		}
+		if got := SelectsStar(stmt); got != tc.want {
+			t.Errorf("SelectsStar(%q) = %v, want %v", tc.sql, got, tc.want)
+		}
+	}
+}
+
+func TestTablesReferenced(t *testing.T) {
+	stmt, err := CheckReadOnly("SELECT users.id FROM users JOIN orders ON users.id = orders.user_id")
+	if err != nil {
+		t.Fatalf("CheckReadOnly error = %v", err)
+	}
+	tables := TablesReferenced(stmt)
+	want := []string{"users", "orders"}
+	if len(tables) != len(want) {
+		t.Fatalf("TablesReferenced = %v, want %v", tables, want)
+	}
+	for i, name := range want {
+		if tables[i] != name {
+			t.Fatalf("TablesReferenced = %v, want %v", tables, want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	rows := [][]interface{}{{1}, {2}, {3}}
+	got, truncated := Truncate(rows, 2)
+	if !truncated || len(got) != 2 {
+		t.Fatalf("Truncate = %v, %v, want 2 rows truncated", got, truncated)
+	}
+
+	got, truncated = Truncate(rows, 10)
+	if truncated || len(got) != 3 {
+		t.Fatalf("Truncate = %v, %v, want 3 rows not truncated", got, truncated)
+	}
+}
+
+func TestErrForbiddenStatementWrapping(t *testing.T) {
+	_, err := CheckReadOnly("DELETE FROM users")
+	if !errors.Is(err, ErrForbiddenStatement) {
+		t.Fatalf("expected error to wrap ErrForbiddenStatement, got %v", err)
+	}
+}